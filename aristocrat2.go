@@ -1,72 +1,130 @@
 package main
 
 import (
-	"bufio"
+	"flag"
+	"fmt"
+	"net/http"
 	"os"
 	"strings"
+	"time"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+	"github.com/BenNicholls/Aristocrat2/eval"
+	"github.com/BenNicholls/Aristocrat2/nnue"
+	"github.com/BenNicholls/Aristocrat2/search"
+	"github.com/BenNicholls/Aristocrat2/uci"
 )
 
 //import "github.com/pkg/profile"
 
-var game position
-
-var table *hashTable
-var usingHashtable bool
-
-var engineMode chessInterface
-var calcController calculationController
-
-//engine options
-var hashSize int = 2
-
 func main() {
+	os.Exit(run())
+}
+
+//run does all the work for main, returning the exit code. this way deferred cleanup still happens.
+func run() int {
 	//defer profile.Start(profile.CPUProfile, profile.ProfilePath(".")).Stop()
-	game = newPosition("")
-	initHashTable()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "match":
+			return runMatch(os.Args[2:])
+		case "sprt":
+			return runSPRT(os.Args[2:])
+		case "tune":
+			return runTune(os.Args[2:])
+		case "datagen":
+			return runDatagen(os.Args[2:])
+		}
+	}
+	hashSize := flag.Int("hash", search.DEFAULTHASHSIZE, "hashtable size in MB (0 to disable)")
+	threads := flag.Int("threads", 1, "number of search threads")
+	mode := flag.String("mode", "uci", "interface to start in: cli or uci")
+	perftDepth := flag.Int("perft", 0, "run perft to this depth on the -fen position and exit. with -epd, the max depth to test")
+	fen := flag.String("fen", "startpos", "position for -perft")
+	variant := flag.String("variant", "chess", "rules for -perft: "+strings.Join(board.VariantNames[:], ", "))
+	bench := flag.Bool("bench", false, "run the search benchmark and exit")
+	epd := flag.String("epd", "", "run the perft suite in this EPD file and exit")
+	logFile := flag.String("log", "", "log all engine input and output to this file")
+	serveAddr := flag.String("serve", "", "serve analysis over HTTP on this address (ie. localhost:8080) instead of reading commands")
+	weightsFile := flag.String("weights", "", "load evaluation weights from this file (as written by the tune command)")
+	evalFile := flag.String("evalfile", "", "evaluate with the nnue network in this file")
+	flag.Parse()
 
-	//command line mode
-	engineMode = &UCIinterface{}
+	if *threads < 1 {
+		*threads = 1
+	}
 
-	commandReader := bufio.NewScanner(os.Stdin)
+	if *logFile != "" {
+		stopLogging, err := uci.StartLogging(*logFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not open log file:", err)
+			return 1
+		}
+		defer stopLogging()
+	}
 
-	for engineResult := ""; engineResult != "quit"; commandReader.Scan() {
-		cmd := strings.SplitN(strings.TrimSpace(commandReader.Text()), " ", 2)
-		params := ""
-		if len(cmd) == 2 {
-			params = cmd[1]
+	evaluator := eval.NewEvaluator()
+	if *weightsFile != "" {
+		w, err := eval.LoadWeights(*weightsFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not load weights:", err)
+			return 1
 		}
-		engineResult = engineMode.processCommand(strings.ToLower(cmd[0]), params)
-		switch engineResult {
-		case "quit":
-			return
-		case "uci":
-			engineMode = &UCIinterface{}
-			engineMode.processCommand("uci", "")
-		case "cli":
-			engineMode = &CLIinterface{}
-			engineMode.processCommand("new", "")
+		evaluator.SetWeights(w)
+	}
+	if *evalFile != "" {
+		n, err := nnue.Load(*evalFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not load network:", err)
+			return 1
 		}
+		evaluator.SetNetwork(n)
 	}
-}
 
-func multiThreadedPerft(pos *position, plys int) (nodes int) {
-	list, _ := movegen(pos)
-	if plys <= 0 {
-		return 0
-	} else if plys == 1 {
-		return len(list)
-	}
-	results := make(chan int, len(list))
-	for _, m := range list {
-		nextPosition := *pos
-		nextPosition.doMove(m)
-		go func() {
-			results <- nextPosition.perft(plys - 1)
-		}()
+	engine, err := uci.NewEngine(*mode)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
 	}
-	for i := 0; i < len(list); i++ {
-		nodes += <-results
+	engine.Search().SetHashSize(*hashSize)
+	engine.Search().Threads = *threads
+	engine.Search().SetEvaluator(evaluator)
+
+	//non-interactive modes. these do their thing and exit.
+	if *epd != "" {
+		if !board.RunPerftSuite(*epd, *perftDepth, engine.Search().PerftCache()) {
+			return 1
+		}
+		return 0
+	} else if *perftDepth > 0 {
+		v, ok := board.ParseVariant(*variant)
+		if !ok {
+			fmt.Fprintln(os.Stderr, "Unknown variant:", *variant)
+			return 2
+		}
+		pos, err := board.ParseVariantFEN(*fen, v)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		startTime := time.Now()
+		nodes := board.MultiThreadedPerft(&pos, *perftDepth, engine.Search().PerftCache())
+		fmt.Printf("Perft %d: %d. (%s)\n", *perftDepth, nodes, board.NPS(nodes, time.Since(startTime).Seconds()))
+		return 0
+	} else if *bench {
+		runBench(*hashSize, *threads, evaluator)
+		return 0
+	} else if *serveAddr != "" {
+		fmt.Println("Serving on", *serveAddr)
+		s := newServer(*hashSize, *threads)
+		s.engine.SetEvaluator(evaluator)
+		if err := http.ListenAndServe(*serveAddr, s); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
 	}
 
-	return
+	engine.Run(os.Stdin)
+	return 0
 }
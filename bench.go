@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+	"github.com/BenNicholls/Aristocrat2/eval"
+	"github.com/BenNicholls/Aristocrat2/search"
+)
+
+//depth to search each bench position to
+const BENCHDEPTH int = 4
+
+//positions for the search benchmark. a mix of openings, middlegames and endgames.
+var benchPositions = []string{
+	"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3",
+	"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	"6k1/5p2/6p1/8/7p/8/6PP/6K1 b - - 0 0",
+	"8/8/8/4k3/8/8/3K4/3R4 w - - 0 1",
+}
+
+//searches each of the bench positions to a fixed depth and reports the total nodes and speed. each
+//position gets a fresh engine, so earlier searches don't help later ones.
+func runBench(hashSize, threads int, evaluator *eval.Evaluator) {
+	totalNodes := 0
+	startTime := time.Now()
+	for i, fen := range benchPositions {
+		fmt.Printf("Position %d/%d: %s\n", i+1, len(benchPositions), fen)
+		engine := search.NewEngine()
+		engine.SetHashSize(hashSize)
+		engine.Threads = threads
+		engine.SetEvaluator(evaluator)
+		pos := board.NewPosition(fen)
+		result := engine.Analyse(&pos, search.SearchLimits{Depth: BENCHDEPTH}, nil)
+		fmt.Printf("bestmove %s score %d nodes %d\n", result.BestMove, result.Lines[0].Score, result.Nodes)
+		totalNodes += result.Nodes
+	}
+
+	dur := time.Since(startTime).Seconds()
+	fmt.Println("==========")
+	fmt.Printf("Total time: %.3fs\nNodes searched: %d\nNodes/second: %.0f\n", dur, totalNodes, float64(totalNodes)/dur)
+}
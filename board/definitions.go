@@ -0,0 +1,231 @@
+package board
+
+//import "fmt"
+
+const (
+	WHITE int = 0
+	BLACK int = 1
+)
+
+//multiply a score from white's point of view by this to get it from a colour's point of view (and back)
+var ScoreModifier [2]int = [2]int{1, -1}
+
+const (
+	PAWN int = iota
+	KNIGHT
+	BISHOP
+	ROOK
+	QUEEN
+	KING
+)
+
+var pieceNames [6]string           //long form piece names
+var pieceNamesShort [6]string      //piece names for chess notation
+var pieceNamesDisplay [2][6]string //piece names for display. also the FEN shortform. indexed by colour first.
+var displayLookup map[rune]piece
+
+//directions. NOTE: the order here is important for movegen.
+const (
+	LEFT int = iota
+	UPLEFT
+	UP
+	UPRIGHT
+	RIGHT
+	DOWNRIGHT
+	DOWN
+	DOWNLEFT
+)
+
+//piece move bitboards
+var pawnMoves [2][64]uint64
+var PawnAttacks [2][64]uint64
+var knightMoves [64]uint64
+var kingMoves [64]uint64
+var slidingMoves [8][64]uint64 //indexed by direction
+
+type zobristKeys struct {
+	black     uint64
+	pieces    [2][6][64]uint64
+	enpassant [8]uint64
+	castle    [4]uint64
+	variant   [NUMVARIANTS]uint64          //none for standard chess
+	checks    [2][WINNINGCHECKS + 1]uint64 //by how many checks each side has given, none for no checks
+	pockets   [2][5][MAXPOCKET + 1]uint64  //by how many of each piece are in each pocket, none for none
+}
+
+var zobrist zobristKeys
+
+type piece struct {
+	colour int
+	piece  int
+}
+
+//reads a piece letter in FEN notation (upper case for white)
+func ParsePiece(ch rune) (colour, kind int, ok bool) {
+	p, ok := displayLookup[ch]
+	return p.colour, p.piece, ok
+}
+
+func init() {
+	pieceNames = [6]string{"Pawn", "Knight", "Bishop", "Rook", "Queen", "King"}
+	pieceNamesShort = [6]string{"", "N", "B", "R", "Q", "K"}
+	pieceNamesDisplay = [2][6]string{{"P", "N", "B", "R", "Q", "K"}, {"p", "n", "b", "r", "q", "k"}}
+
+	displayLookup = make(map[rune]piece)
+	displayLookup['P'] = piece{WHITE, PAWN}
+	displayLookup['N'] = piece{WHITE, KNIGHT}
+	displayLookup['B'] = piece{WHITE, BISHOP}
+	displayLookup['R'] = piece{WHITE, ROOK}
+	displayLookup['Q'] = piece{WHITE, QUEEN}
+	displayLookup['K'] = piece{WHITE, KING}
+	displayLookup['p'] = piece{BLACK, PAWN}
+	displayLookup['n'] = piece{BLACK, KNIGHT}
+	displayLookup['b'] = piece{BLACK, BISHOP}
+	displayLookup['r'] = piece{BLACK, ROOK}
+	displayLookup['q'] = piece{BLACK, QUEEN}
+	displayLookup['k'] = piece{BLACK, KING}
+
+	//generate pawn move bitboards
+	for i := 0; i < 64; i++ {
+		if Rank(i) != 8 {
+			pawnMoves[WHITE][i] = SetBit(pawnMoves[WHITE][i], i-8)
+			if Rank(i) == 2 {
+				pawnMoves[WHITE][i] = SetBit(pawnMoves[WHITE][i], i-16)
+			}
+			if File(i) != 1 {
+				PawnAttacks[WHITE][i] = SetBit(PawnAttacks[WHITE][i], i-9)
+			}
+			if File(i) != 8 {
+				PawnAttacks[WHITE][i] = SetBit(PawnAttacks[WHITE][i], i-7)
+			}
+		}
+		if Rank(i) != 1 {
+			pawnMoves[BLACK][i] = SetBit(pawnMoves[BLACK][i], i+8)
+			if Rank(i) == 7 {
+				pawnMoves[BLACK][i] = SetBit(pawnMoves[BLACK][i], i+16)
+			}
+			if File(i) != 1 {
+				PawnAttacks[BLACK][i] = SetBit(PawnAttacks[BLACK][i], i+7)
+			}
+			if File(i) != 8 {
+				PawnAttacks[BLACK][i] = SetBit(PawnAttacks[BLACK][i], i+9)
+			}
+		}
+	}
+
+	//knight move bitboards
+	for i := 0; i < 64; i++ {
+		if Rank(i) <= 6 {
+			if File(i) != 1 {
+				knightMoves[i] = SetBit(knightMoves[i], i-17)
+			}
+			if File(i) != 8 {
+				knightMoves[i] = SetBit(knightMoves[i], i-15)
+			}
+		}
+		if Rank(i) <= 7 {
+			if File(i) >= 3 {
+				knightMoves[i] = SetBit(knightMoves[i], i-10)
+			}
+			if File(i) <= 6 {
+				knightMoves[i] = SetBit(knightMoves[i], i-6)
+			}
+		}
+		if Rank(i) >= 3 {
+			if File(i) != 1 {
+				knightMoves[i] = SetBit(knightMoves[i], i+15)
+			}
+			if File(i) != 8 {
+				knightMoves[i] = SetBit(knightMoves[i], i+17)
+			}
+		}
+		if Rank(i) >= 2 {
+			if File(i) >= 3 {
+				knightMoves[i] = SetBit(knightMoves[i], i+6)
+			}
+			if File(i) <= 6 {
+				knightMoves[i] = SetBit(knightMoves[i], i+10)
+			}
+		}
+	}
+
+	//king move boards
+	for i := 0; i < 64; i++ {
+		if Rank(i) != 1 {
+			kingMoves[i] = SetBit(kingMoves[i], i+8)
+			if File(i) != 1 {
+				kingMoves[i] = SetBit(kingMoves[i], i+7)
+			}
+			if File(i) != 8 {
+				kingMoves[i] = SetBit(kingMoves[i], i+9)
+			}
+		}
+		if Rank(i) != 8 {
+			kingMoves[i] = SetBit(kingMoves[i], i-8)
+			if File(i) != 1 {
+				kingMoves[i] = SetBit(kingMoves[i], i-9)
+			}
+			if File(i) != 8 {
+				kingMoves[i] = SetBit(kingMoves[i], i-7)
+			}
+		}
+		if File(i) != 1 {
+			kingMoves[i] = SetBit(kingMoves[i], i-1)
+		}
+		if File(i) != 8 {
+			kingMoves[i] = SetBit(kingMoves[i], i+1)
+		}
+	}
+
+	//sliding piece move boards
+	for i := 0; i < 64; i++ {
+		offsets := []int{-1, -9, -8, -7, 1, 9, 8, 7}
+		for dir, off := range offsets {
+			ray := i
+			for {
+				if Rank(ray) == 1 && (dir == DOWNLEFT || dir == DOWN || dir == DOWNRIGHT) {
+					break
+				}
+				if Rank(ray) == 8 && (dir == UPLEFT || dir == UP || dir == UPRIGHT) {
+					break
+				}
+				if File(ray) == 1 && (dir == UPLEFT || dir == LEFT || dir == DOWNLEFT) {
+					break
+				}
+				if File(ray) == 8 && (dir == UPRIGHT || dir == RIGHT || dir == DOWNRIGHT) {
+					break
+				}
+				ray += off
+				slidingMoves[dir][i] = SetBit(slidingMoves[dir][i], ray)
+			}
+		}
+	}
+
+	zobrist = zobristKeys{}
+	zobrist.black = generateKey()
+	for i := 0; i < 64; i++ {
+		for p := PAWN; p <= KING; p++ {
+			zobrist.pieces[WHITE][p][i] = generateKey()
+			zobrist.pieces[BLACK][p][i] = generateKey()
+		}
+		if i < 8 {
+			zobrist.enpassant[i] = generateKey()
+			if i < 4 {
+				zobrist.castle[i] = generateKey()
+			}
+		}
+	}
+	for v := STANDARD + 1; v < NUMVARIANTS; v++ {
+		zobrist.variant[v] = generateKey()
+	}
+	for checks := 1; checks <= WINNINGCHECKS; checks++ {
+		zobrist.checks[WHITE][checks] = generateKey()
+		zobrist.checks[BLACK][checks] = generateKey()
+	}
+	for p := PAWN; p <= QUEEN; p++ {
+		for count := 1; count < len(zobrist.pockets[WHITE][p]); count++ {
+			zobrist.pockets[WHITE][p][count] = generateKey()
+			zobrist.pockets[BLACK][p][count] = generateKey()
+		}
+	}
+}
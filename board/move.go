@@ -0,0 +1,219 @@
+package board
+
+import (
+	"fmt"
+	"strings"
+)
+
+//these magical numbers define the spec for the move data structure
+const (
+	//masks
+	M_SPACEMASK = 0b111111
+	M_PIECEMASK = 0b111
+
+	//sizes (bits)
+	M_SPACESIZE = 6
+	M_PIECESIZE = 3
+
+	//offsets
+	M_FROMOFFSET         = 0
+	M_TOOFFSET           = 6
+	M_PIECEOFFSET        = 12
+	M_PROMOTEPIECEOFFSET = 15
+	M_CAPTUREPIECEOFFSET = 18
+
+	//flags
+	M_DROPFLAG     = (1 << 57) //crazyhouse: the piece comes from the pocket. from and to are both the square it's dropped on
+	M_PAWNJUMPFLAG = (1 << 58)
+	M_CASTLEKFLAG  = (1 << 59)
+	M_CASTLEQFLAG  = (1 << 60)
+	M_PROMOTEFLAG  = (1 << 61)
+	M_CAPTUREFLAG  = (1 << 62)
+	M_TURNFLAG     = (1 << 63)
+)
+
+type Move uint64
+
+//From space
+func (m Move) From() int {
+	return int(M_SPACEMASK & (m << M_FROMOFFSET))
+}
+
+//To space
+func (m Move) To() int {
+	return int(M_SPACEMASK & (m >> M_TOOFFSET))
+}
+
+//Piece moving
+func (m Move) Piece() int {
+	return int(M_PIECEMASK & (m >> M_PIECEOFFSET))
+}
+
+//piece to promote to
+func (m Move) PromotedPiece() int {
+	return int(M_PIECEMASK & (m >> M_PROMOTEPIECEOFFSET))
+}
+
+func (m Move) CapturePiece() int {
+	return int(M_PIECEMASK & (m >> M_CAPTUREPIECEOFFSET))
+}
+
+func (m Move) Drop() bool {
+	return M_DROPFLAG&m != 0
+}
+
+func (m Move) PawnJump() bool {
+	return M_PAWNJUMPFLAG&m != 0
+}
+
+func (m Move) CastleK() bool {
+	return M_CASTLEKFLAG&m != 0
+}
+
+func (m Move) CastleQ() bool {
+	return M_CASTLEQFLAG&m != 0
+}
+
+func (m Move) Promote() bool {
+	return M_PROMOTEFLAG&m != 0
+}
+
+func (m Move) Capture() bool {
+	return M_CAPTUREFLAG&m != 0
+}
+
+//0 = WHITE, 1 = BLACK
+func (m Move) Turn() int {
+	if M_TURNFLAG&m == 0 {
+		return WHITE
+	}
+	return BLACK
+}
+
+func (m Move) String() (s string) {
+	if m == Move(0) {
+		return "No move"
+	}
+	if m.CastleK() {
+		return "0-0"
+	} else if m.CastleQ() {
+		return "0-0-0"
+	} else if m.Drop() {
+		return pieceNamesDisplay[WHITE][m.Piece()] + "@" + SquareToAlgebraic(m.To())
+	}
+	s += pieceNamesShort[m.Piece()] + SquareToAlgebraic(m.From())
+	if m.Capture() {
+		s += "x"
+	}
+	s += SquareToAlgebraic(m.To())
+	if m.Promote() {
+		s += "=" + pieceNamesShort[m.PromotedPiece()]
+	}
+	return
+}
+
+func (m Move) UCIstring() (s string) {
+	if m.Drop() { //P@e4
+		return pieceNamesDisplay[WHITE][m.Piece()] + "@" + SquareToAlgebraic(m.To())
+	}
+	s = SquareToAlgebraic(m.From()) + SquareToAlgebraic(m.To())
+	if m.Promote() {
+		s += strings.ToLower(pieceNamesShort[m.PromotedPiece()])
+	}
+	return
+}
+
+func (m Move) Output() {
+	fmt.Println(m.String())
+	if m.Turn() == WHITE {
+		fmt.Println("Piece: White", pieceNames[m.Piece()])
+	} else {
+		fmt.Println("Piece: Black", pieceNames[m.Piece()])
+	}
+	fmt.Println("From", SquareToAlgebraic(m.From()), "to", SquareToAlgebraic(m.To()))
+	if m.Capture() {
+		fmt.Println("It is a capture.")
+	}
+	if m.Promote() {
+		fmt.Println("Promoting to a ", pieceNames[m.PromotedPiece()])
+	}
+}
+
+func packMove(from, to, piece, promotePiece, capturePiece, turn int, capture bool) Move {
+	var m uint64
+
+	//values (right side)
+	m = uint64(capturePiece)
+	m = (m << M_PIECESIZE) | uint64(promotePiece)
+	m = (m << M_PIECESIZE) | uint64(piece)
+	m = (m << M_SPACESIZE) | uint64(to)
+	m = (m << M_SPACESIZE) | uint64(from)
+
+	//flags (left side)
+	if promotePiece != 0 {
+		m = m | M_PROMOTEFLAG
+	}
+	if capture {
+		m = m | M_CAPTUREFLAG
+	}
+	if turn == BLACK {
+		m = m | M_TURNFLAG
+	}
+	if piece == PAWN {
+		if (turn == WHITE && from-to == 16) || (turn == BLACK && from-to == -16) {
+			m = m | M_PAWNJUMPFLAG
+		}
+	}
+
+	return Move(m)
+}
+
+//a crazyhouse drop of a piece from the pocket
+func packDrop(piece, to, turn int) Move {
+	return packMove(to, to, piece, 0, 0, turn, false) | M_DROPFLAG
+}
+
+//finds the legal move in the position matching a move in UCI notation (e2e4, e7e8q). promotions with
+//no piece given are taken to be queen promotions. castling is the king's move (e1g1), or the king taking
+//its rook (e1h1) in chess960 positions. crazyhouse drops are the piece and the square (N@f3).
+func ParseMove(p *Position, s string) (m Move, ok bool) {
+	if len(s) != 4 && len(s) != 5 {
+		return
+	}
+	if s[1] == '@' {
+		return parseDrop(p, s)
+	}
+	for _, sq := range []string{s[:2], s[2:4]} {
+		if sq[0] < 'a' || sq[0] > 'h' || sq[1] < '1' || sq[1] > '8' {
+			return
+		}
+	}
+	from, to := AlgebraicToSquare(s[:2]), AlgebraicToSquare(s[2:4])
+	promote := QUEEN
+	if len(s) == 5 {
+		promote = displayLookup[rune(s[4])].piece
+	}
+
+	list, _ := Movegen(p)
+	for _, lm := range list {
+		if lm.From() == from && lm.To() == to && (!lm.Promote() || lm.PromotedPiece() == promote) {
+			return lm, true
+		}
+	}
+	return
+}
+
+func parseDrop(p *Position, s string) (m Move, ok bool) {
+	dropped, known := displayLookup[rune(s[0])]
+	if len(s) != 4 || !known || s[2] < 'a' || s[2] > 'h' || s[3] < '1' || s[3] > '8' {
+		return
+	}
+	to := AlgebraicToSquare(s[2:])
+	list, _ := Movegen(p)
+	for _, lm := range list {
+		if lm.Drop() && lm.Piece() == dropped.piece && lm.To() == to {
+			return lm, true
+		}
+	}
+	return
+}
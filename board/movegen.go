@@ -0,0 +1,353 @@
+package board
+
+import (
+	"fmt"
+)
+
+type MoveList []Move
+
+func (ml MoveList) Output() {
+	for _, m := range ml {
+		fmt.Println(m.String())
+	}
+	fmt.Println(len(ml), "total moves.")
+}
+
+//returns a string assuming the mvovelist is a Variation
+func (ml MoveList) Variation() (v string) {
+	if len(ml) == 0 {
+		return "No Moves."
+	}
+	for _, m := range ml {
+		v += m.String() + " "
+	}
+	return
+}
+
+//returns the moves in UCI format, one string each
+func (ml MoveList) UCImoves() []string {
+	moves := make([]string, 0, len(ml))
+	for _, m := range ml {
+		moves = append(moves, m.UCIstring())
+	}
+	return moves
+}
+
+//returns the variation in UCI notation
+func (ml MoveList) UCIvariation() (v string) {
+	for i, m := range ml {
+		if i > 0 {
+			v += " "
+		}
+		v += m.UCIstring()
+	}
+	return
+}
+
+func (ml MoveList) Contains(m Move) bool {
+	for _, lm := range ml {
+		if lm == m {
+			return true
+		}
+	}
+	return false
+}
+
+//the first and last ranks, where pawns can't be dropped
+const backRanks uint64 = 0xFF | 0xFF<<56
+
+func Movegen(pos *Position) (MoveList, int) {
+	//variants can end the game while there are moves on the board
+	if pos.Variant != STANDARD && pos.variantOutcome() != Ongoing {
+		return MoveList{}, 0
+	}
+
+	captureList := make(MoveList, 0, 10)
+	nonCaptureList := make(MoveList, 0, 20)
+
+	var pieces uint64
+	var opponentPieces uint64
+	occupied := pos.Colours[WHITE] | pos.Colours[BLACK]
+	pieces = pos.Colours[pos.ToMove]
+	opponentPieces = pos.Colours[Opponent(pos.ToMove)]
+
+	//generate pawn moves
+	ForEachBit(pieces&pos.Pieces[PAWN], func(fromSquare int) {
+		moves := pawnMoves[pos.ToMove][fromSquare] &^ occupied
+		if pos.ToMove == WHITE && Rank(fromSquare) == 2 && !CheckBit(moves, fromSquare-8) {
+			moves = ClearBit(moves, fromSquare-16)
+		} else if pos.ToMove == BLACK && Rank(fromSquare) == 7 && !CheckBit(moves, fromSquare+8) {
+			moves = ClearBit(moves, fromSquare+16)
+		}
+		ForEachBit(moves, func(toSquare int) {
+			if (pos.ToMove == WHITE && Rank(toSquare) == 8) || (pos.ToMove == BLACK && Rank(toSquare) == 1) {
+				addPromosToMovelist(pos, &nonCaptureList, fromSquare, toSquare, pos.ToMove, false)
+			} else {
+				addToMovelist(pos, &nonCaptureList, packMove(fromSquare, toSquare, PAWN, 0, 0, pos.ToMove, false))
+			}
+		})
+		captures := PawnAttacks[pos.ToMove][fromSquare] & (SetBit(opponentPieces, pos.Enpassant))
+		ForEachBit(captures, func(toSquare int) {
+			if (pos.ToMove == WHITE && Rank(toSquare) == 8) || (pos.ToMove == BLACK && Rank(toSquare) == 1) {
+				addPromosToMovelist(pos, &captureList, fromSquare, toSquare, pos.ToMove, true)
+			} else {
+				if toSquare == pos.Enpassant {
+					addToMovelist(pos, &captureList, packMove(fromSquare, toSquare, PAWN, 0, PAWN, pos.ToMove, true))
+				} else {
+					addToMovelist(pos, &captureList, packMove(fromSquare, toSquare, PAWN, 0, pos.GetPieceOnSquare(toSquare), pos.ToMove, true))
+				}
+			}
+		})
+	})
+
+	//workin on them knight moves
+	ForEachBit(pieces&pos.Pieces[KNIGHT], func(fromSquare int) {
+		moves := knightMoves[fromSquare] &^ pieces
+		ForEachBit(moves, func(toSquare int) {
+			if CheckBit(opponentPieces, toSquare) {
+				addToMovelist(pos, &captureList, packMove(fromSquare, toSquare, KNIGHT, 0, pos.GetPieceOnSquare(toSquare), pos.ToMove, true))
+			} else {
+				addToMovelist(pos, &nonCaptureList, packMove(fromSquare, toSquare, KNIGHT, 0, 0, pos.ToMove, false))
+			}
+		})
+	})
+
+	//king moves. there's only ever one king, except in antichess where they can be promoted to or lost
+	ForEachBit(pieces&pos.Pieces[KING], func(fromSquare int) {
+		moves := kingMoves[fromSquare] &^ pieces
+		ForEachBit(moves, func(toSquare int) {
+			if CheckBit(opponentPieces, toSquare) {
+				addToMovelist(pos, &captureList, packMove(fromSquare, toSquare, KING, 0, pos.GetPieceOnSquare(toSquare), pos.ToMove, true))
+			} else {
+				addToMovelist(pos, &nonCaptureList, packMove(fromSquare, toSquare, KING, 0, 0, pos.ToMove, false))
+			}
+		})
+		if pos.Variant != ANTICHESS && !pos.InCheck() { //can't castle out of check
+			for i, right := range pos.castleRights() {
+				if *right && i/2 == pos.ToMove {
+					addCastleToMovelist(pos, &nonCaptureList, fromSquare, i)
+				}
+			}
+		}
+	})
+
+	//bishop
+	ForEachBit(pieces&pos.Pieces[BISHOP], func(fromSquare int) {
+		var moves uint64
+		for dir := UPLEFT; dir <= DOWNLEFT; dir += 2 {
+			rayMoves := slidingMoves[dir][fromSquare]
+			if rayMoves&occupied != 0 {
+				var endSquare int
+				if dir <= UPRIGHT {
+					endSquare = RightBit(rayMoves & occupied)
+				} else {
+					endSquare = LeftBit(rayMoves & occupied)
+				}
+
+				moves = moves | (slidingMoves[dir][fromSquare] &^ slidingMoves[dir][endSquare])
+				moves = ClearBit(moves, endSquare)
+
+				if CheckBit(opponentPieces, endSquare) {
+					addToMovelist(pos, &captureList, packMove(fromSquare, endSquare, BISHOP, 0, pos.GetPieceOnSquare(endSquare), pos.ToMove, true))
+				}
+			} else {
+				moves = moves | slidingMoves[dir][fromSquare]
+			}
+		}
+		ForEachBit(moves, func(toSquare int) {
+			addToMovelist(pos, &nonCaptureList, packMove(fromSquare, toSquare, BISHOP, 0, 0, pos.ToMove, false))
+		})
+	})
+
+	//rook
+	ForEachBit(pieces&pos.Pieces[ROOK], func(fromSquare int) {
+		var moves uint64
+		for dir := LEFT; dir <= DOWN; dir += 2 {
+			rayMoves := slidingMoves[dir][fromSquare]
+			if rayMoves&occupied != 0 {
+				var endSquare int
+				if dir <= UPRIGHT {
+					endSquare = RightBit(rayMoves & occupied)
+				} else {
+					endSquare = LeftBit(rayMoves & occupied)
+				}
+
+				moves = moves | (slidingMoves[dir][fromSquare] &^ slidingMoves[dir][endSquare])
+				moves = ClearBit(moves, endSquare)
+
+				if CheckBit(opponentPieces, endSquare) {
+					addToMovelist(pos, &captureList, packMove(fromSquare, endSquare, ROOK, 0, pos.GetPieceOnSquare(endSquare), pos.ToMove, true))
+				}
+			} else {
+				moves = moves | slidingMoves[dir][fromSquare]
+			}
+		}
+		ForEachBit(moves, func(toSquare int) {
+			addToMovelist(pos, &nonCaptureList, packMove(fromSquare, toSquare, ROOK, 0, 0, pos.ToMove, false))
+		})
+	})
+
+	//queen
+	ForEachBit(pieces&pos.Pieces[QUEEN], func(fromSquare int) {
+		var moves uint64
+		for dir := LEFT; dir <= DOWNLEFT; dir++ {
+			rayMoves := slidingMoves[dir][fromSquare]
+			if rayMoves&occupied != 0 {
+				var endSquare int
+				if dir <= UPRIGHT {
+					endSquare = RightBit(rayMoves & occupied)
+				} else {
+					endSquare = LeftBit(rayMoves & occupied)
+				}
+
+				moves = moves | (slidingMoves[dir][fromSquare] &^ slidingMoves[dir][endSquare])
+				moves = ClearBit(moves, endSquare)
+
+				if CheckBit(opponentPieces, endSquare) {
+					addToMovelist(pos, &captureList, packMove(fromSquare, endSquare, QUEEN, 0, pos.GetPieceOnSquare(endSquare), pos.ToMove, true))
+				}
+			} else {
+				moves = moves | slidingMoves[dir][fromSquare]
+			}
+		}
+		ForEachBit(moves, func(toSquare int) {
+			addToMovelist(pos, &nonCaptureList, packMove(fromSquare, toSquare, QUEEN, 0, 0, pos.ToMove, false))
+		})
+	})
+
+	//crazyhouse drops, onto any empty square. pawns can't go on the first or last rank
+	if pos.Variant == CRAZYHOUSE {
+		for piece := PAWN; piece <= QUEEN; piece++ {
+			if pos.Pockets[pos.ToMove][piece] == 0 {
+				continue
+			}
+			squares := ^occupied
+			if piece == PAWN {
+				squares &^= backRanks
+			}
+			ForEachBit(squares, func(toSquare int) {
+				addToMovelist(pos, &nonCaptureList, packDrop(piece, toSquare, pos.ToMove))
+			})
+		}
+	}
+
+	//captures are compulsory in antichess
+	if pos.Variant == ANTICHESS && len(captureList) > 0 {
+		return captureList, len(captureList)
+	}
+
+	list := append(captureList, nonCaptureList...)
+	return list, len(captureList)
+}
+
+func addToMovelist(pos *Position, ml *MoveList, m Move) {
+	//antichess has no check, so every move is legal
+	if pos.Variant == ANTICHESS {
+		*ml = append(*ml, m)
+		return
+	} else if pos.Variant == ATOMIC {
+		if pos.atomicLegal(m) {
+			*ml = append(*ml, m)
+		}
+		return
+	}
+
+	//drops only fill a square, so they're fine unless the king is in check and they don't block it
+	if m.Drop() {
+		pos.Colours[pos.ToMove] = SetBit(pos.Colours[pos.ToMove], m.To())
+		if !pos.IsSquareAttacked(pos.GetKingSquare(pos.ToMove), Opponent(pos.ToMove)) {
+			*ml = append(*ml, m)
+		}
+		pos.Colours[pos.ToMove] = ClearBit(pos.Colours[pos.ToMove], m.To())
+		return
+	}
+
+	//do a temporary shuffle so we can check move legality
+	pos.Colours[pos.ToMove] = ClearBit(pos.Colours[pos.ToMove], m.From())
+	pos.Colours[pos.ToMove] = SetBit(pos.Colours[pos.ToMove], m.To())
+
+	//special case for captures, need to temporarily remove the captured piece as well
+	if m.Capture() {
+		if m.To() == pos.Enpassant {
+			var captureSquare int
+			if pos.ToMove == WHITE {
+				captureSquare = pos.Enpassant + 8
+			} else {
+				captureSquare = pos.Enpassant - 8
+			}
+			pos.Colours[Opponent(pos.ToMove)] = ClearBit(pos.Colours[Opponent(pos.ToMove)], captureSquare)
+			defer func() { pos.Colours[Opponent(pos.ToMove)] = SetBit(pos.Colours[Opponent(pos.ToMove)], captureSquare) }()
+		} else {
+			pos.Colours[Opponent(pos.ToMove)] = ClearBit(pos.Colours[Opponent(pos.ToMove)], m.To())
+			defer func() { pos.Colours[Opponent(pos.ToMove)] = SetBit(pos.Colours[Opponent(pos.ToMove)], m.To()) }()
+		}
+	}
+
+	if m.Piece() == KING {
+		if !pos.IsSquareAttacked(m.To(), Opponent(pos.ToMove)) {
+			*ml = append(*ml, m)
+		}
+	} else if !pos.IsSquareAttacked(pos.GetKingSquare(pos.ToMove), Opponent(pos.ToMove)) {
+		*ml = append(*ml, m)
+	}
+
+	//put everything back now that we're done :)
+	pos.Colours[pos.ToMove] = ClearBit(pos.Colours[pos.ToMove], m.To())
+	pos.Colours[pos.ToMove] = SetBit(pos.Colours[pos.ToMove], m.From())
+}
+
+//adds castling (in the order of CastleRooks) if it's allowed. in chess960 the king and rook can start
+//anywhere on the back rank, but they always end up where they would in normal chess.
+func addCastleToMovelist(pos *Position, ml *MoveList, kingSquare, i int) {
+	rookSquare, kingTarget, rookTarget := pos.CastleRooks[i], castleKingTargets[i], castleRookTargets[i]
+
+	//everything the king and rook pass over or land on has to be empty, apart from the two of them
+	others := ClearBit(ClearBit(pos.Colours[WHITE]|pos.Colours[BLACK], kingSquare), rookSquare)
+	for square := min(kingSquare, rookSquare, kingTarget, rookTarget); square <= max(kingSquare, rookSquare, kingTarget, rookTarget); square++ {
+		if CheckBit(others, square) {
+			return
+		}
+	}
+
+	//can't castle through or into check either. the king's target is checked with the rook taken off, in
+	//case the rook was blocking an attack on it
+	for square := min(kingSquare, kingTarget); square <= max(kingSquare, kingTarget); square++ {
+		if square != kingTarget && pos.kingAttacked(square) {
+			return
+		}
+	}
+	colour := pos.Colours[pos.ToMove]
+	pos.Colours[pos.ToMove] = ClearBit(colour, rookSquare)
+	attacked := pos.kingAttacked(kingTarget)
+	pos.Colours[pos.ToMove] = colour
+	if attacked {
+		return
+	}
+
+	to, flag := kingTarget, Move(M_CASTLEKFLAG)
+	if pos.Chess960 {
+		to = rookSquare //king takes rook
+	}
+	if i%2 == 1 {
+		flag = M_CASTLEQFLAG
+	}
+	*ml = append(*ml, packMove(kingSquare, to, KING, 0, 0, pos.ToMove, false)|flag)
+}
+
+//hi future ben. You're wondering why this isn't a loop. Well apparently having it as a loop
+//breaks the go compiler for reasons that are ungooglable. something about it trying to use
+//invalid asm instructions under certain inputs. yeah. for some reason manually unrolling
+//the loop works so don't touch it.
+func addPromosToMovelist(pos *Position, list *MoveList, from, to, turn int, capture bool) {
+	capturePiece := 0
+	if capture {
+		capturePiece = pos.GetPieceOnSquare(to)
+	}
+	addToMovelist(pos, list, packMove(from, to, PAWN, KNIGHT, capturePiece, turn, capture))
+	addToMovelist(pos, list, packMove(from, to, PAWN, BISHOP, capturePiece, turn, capture))
+	addToMovelist(pos, list, packMove(from, to, PAWN, ROOK, capturePiece, turn, capture))
+	addToMovelist(pos, list, packMove(from, to, PAWN, QUEEN, capturePiece, turn, capture))
+	if pos.Variant == ANTICHESS {
+		addToMovelist(pos, list, packMove(from, to, PAWN, KING, capturePiece, turn, capture))
+	}
+}
@@ -0,0 +1,216 @@
+package board
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//PerftCache stores perft results so transpositions only get counted once. search engines have one.
+//perft works fine without one (pass nil), just slower.
+type PerftCache interface {
+	LoadPerft(hash uint64, depth int) (nodes int, ok bool)
+	StorePerft(hash uint64, depth, nodes int)
+}
+
+func (p Position) Perft(n int, cache PerftCache) (nodes int) {
+	if cache != nil {
+		if nodes, ok := cache.LoadPerft(p.Hash, n); ok {
+			return nodes
+		}
+	}
+	list, _ := Movegen(&p)
+	if n == 1 {
+		return len(list)
+	}
+
+	for _, m := range list {
+		nextPosition := p
+		nextPosition.DoMove(m)
+		nodes += nextPosition.Perft(n-1, cache)
+	}
+
+	if cache != nil {
+		cache.StorePerft(p.Hash, n, nodes)
+	}
+	return
+}
+
+func (p Position) Divide(n int, cache PerftCache) {
+	if n == 1 {
+		return
+	}
+	list, _ := Movegen(&p)
+	total := 0
+	for _, m := range list {
+		nextPosition := p
+		nextPosition.DoMove(m)
+		p := MultiThreadedPerft(&nextPosition, n-1, cache)
+		total += p
+		fmt.Println(m.String()+":", p)
+	}
+
+	fmt.Println("Total: ", total)
+	return
+}
+
+func MultiThreadedPerft(pos *Position, plys int, cache PerftCache) (nodes int) {
+	list, _ := Movegen(pos)
+	if plys <= 0 {
+		return 0
+	} else if plys == 1 {
+		return len(list)
+	}
+	results := make(chan int, len(list))
+	for _, m := range list {
+		nextPosition := pos.Copy() //each thread appends to its own move history
+		nextPosition.DoMove(m)
+		go func() {
+			results <- nextPosition.Perft(plys-1, cache)
+		}()
+	}
+	for i := 0; i < len(list); i++ {
+		nodes += <-results
+	}
+
+	return
+}
+
+//MultiThreadedPerft that gives up once stop is closed. stop isn't checked in the last few plies, so it
+//doesn't slow down the bulk of the counting.
+func PerftUntil(pos *Position, plys int, cache PerftCache, stop <-chan struct{}) (nodes int, stopped bool) {
+	if plys <= perftStopPlys {
+		return MultiThreadedPerft(pos, plys, cache), false
+	}
+	list, _ := Movegen(pos)
+	results := make(chan int, len(list))
+	for _, m := range list {
+		nextPosition := pos.Copy()
+		nextPosition.DoMove(m)
+		go func() {
+			nodes, stopped := nextPosition.perftUntil(plys-1, cache, stop)
+			if stopped {
+				nodes = -1
+			}
+			results <- nodes
+		}()
+	}
+	for i := 0; i < len(list); i++ {
+		if n := <-results; n < 0 {
+			stopped = true
+		} else {
+			nodes += n
+		}
+	}
+	return nodes, stopped
+}
+
+//plies from the leaves that PerftUntil counts without checking for stop
+const perftStopPlys int = 4
+
+func (p Position) perftUntil(n int, cache PerftCache, stop <-chan struct{}) (nodes int, stopped bool) {
+	if n <= perftStopPlys {
+		return p.Perft(n, cache), false
+	}
+	select {
+	case <-stop:
+		return 0, true
+	default:
+	}
+	list, _ := Movegen(&p)
+	for _, m := range list {
+		nextPosition := p
+		nextPosition.DoMove(m)
+		count, stopped := nextPosition.perftUntil(n-1, cache, stop)
+		if stopped {
+			return 0, true
+		}
+		nodes += count
+	}
+	return nodes, false
+}
+
+type perftTest struct {
+	fen    string
+	depths []int
+	vals   []int
+}
+
+//reads a perft suite in EPD format. each line is a FEN followed by the expected node counts for
+//each depth, ie: <fen> ;D1 20 ;D2 400
+func loadPerftSuite(filename string) (tests []perftTest, err error) {
+	testSuite, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer testSuite.Close()
+
+	suiteReader := bufio.NewScanner(testSuite)
+	for suiteReader.Scan() {
+		if strings.TrimSpace(suiteReader.Text()) == "" {
+			continue
+		}
+		line := strings.SplitN(strings.TrimSpace(suiteReader.Text()), ";", 2)
+		if len(line) != 2 {
+			return nil, errors.New("No perft results in line: " + suiteReader.Text())
+		}
+		test := perftTest{fen: strings.TrimSpace(line[0])}
+		for _, v := range strings.Split(line[1], ";") {
+			v = strings.TrimSpace(v)
+			if len(v) < 4 {
+				return nil, errors.New("Bad perft result: " + v)
+			}
+			num, err := strconv.Atoi(strings.TrimSpace(v[3:]))
+			if err != nil {
+				return nil, errors.New("Bad conversion: " + v[3:])
+			}
+			depth, err := strconv.Atoi(strings.TrimSpace(v[1:2]))
+			if err != nil {
+				return nil, errors.New("Bad conversion: " + v[1:2])
+			}
+			test.vals = append(test.vals, num)
+			test.depths = append(test.depths, depth)
+		}
+		tests = append(tests, test)
+	}
+
+	return tests, suiteReader.Err()
+}
+
+//runs every test in the perft suite, up to maxDepth (0 for no limit). reports whether they all passed.
+func RunPerftSuite(filename string, maxDepth int, cache PerftCache) bool {
+	tests, err := loadPerftSuite(filename)
+	if err != nil {
+		fmt.Println("Could not load perft suite:", err)
+		return false
+	}
+
+	passed := true
+	startTime := time.Now()
+	for tNum, perft := range tests {
+		pos := NewPosition(perft.fen)
+		for i, val := range perft.vals {
+			if maxDepth > 0 && perft.depths[i] > maxDepth {
+				break
+			}
+			fmt.Printf("(%d/%d) %s | depth: %d, expecting %d ...", tNum+1, len(tests), perft.fen, perft.depths[i], val)
+			if n := MultiThreadedPerft(&pos, perft.depths[i], cache); n != val {
+				fmt.Printf("NO! got %d\n", n)
+				passed = false
+				break
+			}
+			fmt.Println("YES!")
+		}
+	}
+
+	if passed {
+		fmt.Printf("All perft tests passed. (%.3fs)\n", time.Since(startTime).Seconds())
+	} else {
+		fmt.Println("PERFT SUITE FAILED")
+	}
+	return passed
+}
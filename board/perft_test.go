@@ -0,0 +1,57 @@
+package board
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+//a simple always-replace cache, so the suite doesn't take forever
+type perftTable struct {
+	sync.Mutex
+	entries []perftEntry
+}
+
+type perftEntry struct {
+	hash  uint64
+	depth int
+	nodes int
+}
+
+func (pt *perftTable) LoadPerft(hash uint64, depth int) (int, bool) {
+	pt.Lock()
+	defer pt.Unlock()
+	e := pt.entries[hash%uint64(len(pt.entries))]
+	return e.nodes, e.hash == hash && e.depth == depth
+}
+
+func (pt *perftTable) StorePerft(hash uint64, depth, nodes int) {
+	pt.Lock()
+	pt.entries[hash%uint64(len(pt.entries))] = perftEntry{hash, depth, nodes}
+	pt.Unlock()
+}
+
+func TestPerft(t *testing.T) {
+	cache := &perftTable{entries: make([]perftEntry, 1<<22)}
+
+	tests, err := loadPerftSuite("../test/perftSuite.epd")
+	if err != nil {
+		t.Error("Could not open perft test suite:", err)
+		return
+	}
+
+	for tNum, perft := range tests {
+		pos := NewPosition(perft.fen)
+		for i, val := range perft.vals {
+			fmt.Printf("(%d/%d) %s | depth: %d, expecting %d ...", tNum+1, len(tests), perft.fen, perft.depths[i], val)
+			n := MultiThreadedPerft(&pos, perft.depths[i], cache)
+			if n != val {
+				fmt.Println("NO!")
+				t.Errorf("PERFT FAIL: %s, depth %d. Expected %d, got %d", perft.fen, perft.depths[i], val, n)
+				break
+			} else {
+				fmt.Println("YES!")
+			}
+		}
+	}
+}
@@ -0,0 +1,735 @@
+//Package board has the chess rules: positions, moves, move generation, FEN parsing and perft.
+package board
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Position struct {
+	ToMove int
+
+	CastleWK bool
+	CastleWQ bool
+	CastleBK bool
+	CastleBQ bool
+
+	CastleRooks [4]int //where the castling rooks start, in the order WK, WQ, BK, BQ. the corners, except in chess960
+	Chess960    bool   //castling is written king-takes-rook (e1h1) in UCI notation, and FENs use X-FEN castling
+
+	Enpassant int
+
+	Variant Variant //the rules the game is played by. standard chess unless it's set up otherwise
+	Checks  [2]int  //three-check: how many checks each side has given
+
+	Pockets  [2][5]int //crazyhouse: pieces each side has captured and can drop, by kind. kings never are
+	Promoted uint64    //crazyhouse: pieces that were pawns, which go back to being pawns when captured
+
+	FiftyMoveCounter int
+	FullMoveCounter  int
+
+	MoveHistory []Move
+
+	//bitboards! so many bitboards!
+	Colours [2]uint64 //one for each colour. OR these together to get the occupied board
+	Pieces  [6]uint64 //one for each kind of piece
+
+	Hash uint64 //zobrist hash. generated at start, then incrementally updated.
+
+	changes    [16]PieceChange //pieces the last move put on or took off the board, in order. see PieceChanges
+	numChanges int
+}
+
+//PieceChange is a piece put on the board or taken off it by a move, packed into 11 bits: the square,
+//then the kind of piece, the colour and whether it was added
+type PieceChange uint16
+
+const pieceAdded PieceChange = 1 << 10
+
+func (c PieceChange) Square() int {
+	return int(c & 63)
+}
+
+func (c PieceChange) Piece() int {
+	return int(c>>6) & 7
+}
+
+func (c PieceChange) Colour() int {
+	return int(c>>9) & 1
+}
+
+func (c PieceChange) Added() bool {
+	return c&pieceAdded != 0
+}
+
+//most pieces one move can put on or take off the board: an atomic capture moves the capturer onto its
+//square, then blows it up along with what it took and up to 8 pieces around it
+const MAXPIECECHANGES int = 12
+
+//returns a Copy of the position that doesn't share its move history with the original, so one can be
+//searched while the other carries on being played.
+func (p *Position) Copy() (c Position) {
+	c = *p
+	c.MoveHistory = make([]Move, len(p.MoveHistory), cap(p.MoveHistory))
+	copy(c.MoveHistory, p.MoveHistory)
+	return
+}
+
+func NewPosition(fen string) (pos Position) {
+	pos = Position{}
+	pos.MoveHistory = make([]Move, 0, 20)
+
+	if fen == "" || fen == "startpos" {
+		fen = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	}
+
+	fenPieces := pos.parseVariantFields(strings.Split(strings.TrimSpace(fen), " "))
+
+	posString := strings.NewReader(fenPieces[0])
+	square := 0
+	for ch, _, err := posString.ReadRune(); err == nil; ch, _, err = posString.ReadRune() {
+		p, ok := displayLookup[ch]
+		if ok {
+			pos.AddPiece(p.colour, p.piece, square)
+			square++
+		} else if ch == '/' {
+			continue
+		} else if ch == '~' { //crazyhouse: the piece before was promoted
+			pos.Promoted = SetBit(pos.Promoted, square-1)
+		} else { //number indicating empty spaces
+			square += int(ch - '0')
+		}
+	}
+
+	if fenPieces[1] == "w" {
+		pos.ToMove = WHITE
+	} else if fenPieces[1] == "b" {
+		pos.ToMove = BLACK
+	}
+
+	pos.parseCastling(fenPieces[2])
+
+	if fenPieces[3] != "-" {
+		pos.Enpassant = AlgebraicToSquare(fenPieces[3])
+	} else {
+		pos.Enpassant = -1
+	}
+
+	if len(fenPieces) >= 5 && fenPieces[4] != "" {
+		pos.FiftyMoveCounter, _ = strconv.Atoi(fenPieces[4])
+	}
+
+	pos.FullMoveCounter = 1
+	if len(fenPieces) >= 6 {
+		if n, err := strconv.Atoi(fenPieces[5]); err == nil && n > 0 {
+			pos.FullMoveCounter = n
+		}
+	}
+
+	pos.Hash = pos.generateZobristHash()
+	pos.numChanges = 0 //setting up the board isn't a move
+
+	return
+}
+
+//the corners, where the rooks start in normal chess
+var standardCastleRooks = [4]int{63, 56, 7, 0}
+
+//where the king and rook end up after each castling, whatever the variant: the g and f files or the c and
+//d files
+var castleKingTargets = [4]int{62, 58, 6, 2}
+var castleRookTargets = [4]int{61, 59, 5, 3}
+
+//castling rights, in the same order as CastleRooks and the zobrist keys
+func (p *Position) castleRights() [4]*bool {
+	return [4]*bool{&p.CastleWK, &p.CastleWQ, &p.CastleBK, &p.CastleBQ}
+}
+
+//reads the castling field of a FEN. as well as KQkq, this takes the rook files of X-FEN and Shredder-FEN
+//(ie. HAha), where KQkq mean the outermost rook on that side of the king.
+func (p *Position) parseCastling(field string) {
+	p.CastleRooks = standardCastleRooks
+	for _, ch := range field {
+		colour := WHITE
+		if ch >= 'a' && ch <= 'z' {
+			colour, ch = BLACK, ch-'a'+'A'
+		}
+		king := p.GetKingSquare(colour)
+		if p.Pieces[KING]&p.Colours[colour] == 0 || Rank(king) != 1+7*colour {
+			continue
+		}
+
+		rook := -1
+		switch {
+		case ch == 'K':
+			rook = p.outermostRook(colour, true)
+		case ch == 'Q':
+			rook = p.outermostRook(colour, false)
+		case ch >= 'A' && ch <= 'H':
+			rook = king - File(king) + 1 + int(ch-'A')
+			p.Chess960 = true
+		}
+		if rook < 0 || !CheckBit(p.Colours[colour]&p.Pieces[ROOK], rook) {
+			continue
+		}
+
+		i := colour * 2
+		if rook < king {
+			i++
+		}
+		*p.castleRights()[i] = true
+		p.CastleRooks[i] = rook
+		if rook != standardCastleRooks[i] || File(king) != 5 {
+			p.Chess960 = true
+		}
+	}
+}
+
+//the rook on the back rank furthest from the king on one side, or -1 if there isn't one
+func (p *Position) outermostRook(colour int, kingside bool) int {
+	king := p.GetKingSquare(colour)
+	first, step := king-File(king)+1, 1
+	if kingside {
+		first, step = king-File(king)+8, -1
+	}
+	for square := first; square != king; square += step {
+		if CheckBit(p.Colours[colour]&p.Pieces[ROOK], square) {
+			return square
+		}
+	}
+	return -1
+}
+
+//like newPosition, but checks the FEN first. for FENs from outside sources that might be garbage.
+func ParseFEN(fen string) (pos Position, err error) {
+	return ParseVariantFEN(fen, STANDARD)
+}
+
+//like ParseFEN, for a position played by the variant's rules. variants the FEN itself says (three-check
+//counters or crazyhouse pockets) don't need to be passed in.
+func ParseVariantFEN(fen string, v Variant) (pos Position, err error) {
+	if fen == "" || fen == "startpos" {
+		pos = NewPosition(fen)
+		if v != STANDARD {
+			pos.SetVariant(v)
+		}
+		return pos, nil
+	}
+
+	fenPieces := strings.Fields(fen)
+	if len(fenPieces) < 4 {
+		return pos, errors.New("FEN needs at least 4 fields: " + fen)
+	}
+
+	boardField, pocket, hasPocket := strings.Cut(fenPieces[0], "[") //crazyhouse pockets come after the board
+	ranks := strings.Split(boardField, "/")
+	if len(ranks) != 8 {
+		return pos, errors.New("FEN board needs 8 ranks: " + fenPieces[0])
+	}
+	kings := [2]int{}
+	pieces := [KING]int{} //of each kind on the board and in the pockets, with promoted pieces as pawns
+	for _, rank := range ranks {
+		squares := 0
+		last := -1
+		for _, ch := range rank {
+			if p, ok := displayLookup[ch]; ok {
+				if p.piece == KING {
+					kings[p.colour]++
+				} else {
+					pieces[p.piece]++
+				}
+				last = p.piece
+				squares++
+			} else if ch >= '1' && ch <= '8' {
+				squares += int(ch - '0')
+				last = -1
+			} else if ch == '~' && squares > 0 {
+				if last >= 0 && last != KING {
+					pieces[last]--
+					pieces[PAWN]++
+				}
+				last = -1
+				continue
+			} else {
+				return pos, errors.New("Bad character in FEN board: " + string(ch))
+			}
+		}
+		if squares != 8 {
+			return pos, errors.New("FEN rank doesn't have 8 squares: " + rank)
+		}
+	}
+	//pieces only change hands in crazyhouse, so a pocket can only overflow if there are more of a kind
+	//than it can hold in the whole game
+	if hasPocket || v == CRAZYHOUSE {
+		for _, ch := range strings.TrimSuffix(pocket, "]") {
+			if p, ok := displayLookup[ch]; ok && p.piece != KING {
+				pieces[p.piece]++
+			}
+		}
+		for piece, n := range pieces {
+			if n > MAXPOCKET {
+				return pos, fmt.Errorf("FEN has %d %ss, more than the %d a pocket can hold", n, pieceNames[piece], MAXPOCKET)
+			}
+		}
+	}
+	//kings are like any other piece in antichess: they can be taken, and pawns can promote to them
+	if v != ANTICHESS && (kings[WHITE] != 1 || kings[BLACK] != 1) {
+		return pos, errors.New("FEN board needs exactly one king for each side")
+	}
+
+	if fenPieces[1] != "w" && fenPieces[1] != "b" {
+		return pos, errors.New("Bad side to move in FEN: " + fenPieces[1])
+	}
+	if strings.Trim(fenPieces[2], "KQkqABCDEFGHabcdefgh") != "" && fenPieces[2] != "-" {
+		return pos, errors.New("Bad castling rights in FEN: " + fenPieces[2])
+	}
+	if ep := fenPieces[3]; ep != "-" && (len(ep) != 2 || ep[0] < 'a' || ep[0] > 'h' || (ep[1] != '3' && ep[1] != '6')) {
+		return pos, errors.New("Bad en passant square in FEN: " + ep)
+	}
+
+	pos = NewPosition(fen)
+	if v != STANDARD {
+		pos.SetVariant(v)
+	}
+	//look from the other side, so the variant's idea of check gets used
+	pos.ToMove = Opponent(pos.ToMove)
+	inCheck := pos.InCheck()
+	pos.ToMove = Opponent(pos.ToMove)
+	if inCheck {
+		return pos, errors.New("Side not to move is in check")
+	}
+	return pos, nil
+}
+
+//returns the position flipped top to bottom with the colours swapped: the same position for the other
+//side. anything that treats the colours the same should treat both alike. the move history isn't kept.
+func (p *Position) Mirror() (m Position) {
+	m.MoveHistory = make([]Move, 0, 20)
+	for colour := WHITE; colour <= BLACK; colour++ {
+		for piece := PAWN; piece <= KING; piece++ {
+			ForEachBit(p.Colours[colour]&p.Pieces[piece], func(square int) {
+				m.AddPiece(Opponent(colour), piece, square^56)
+			})
+		}
+	}
+
+	m.ToMove = Opponent(p.ToMove)
+	m.CastleWK, m.CastleWQ, m.CastleBK, m.CastleBQ = p.CastleBK, p.CastleBQ, p.CastleWK, p.CastleWQ
+	for i, rook := range p.CastleRooks {
+		m.CastleRooks[i^2] = rook ^ 56
+	}
+	m.Chess960 = p.Chess960
+	m.Variant, m.Checks = p.Variant, [2]int{p.Checks[BLACK], p.Checks[WHITE]}
+	m.Pockets = [2][5]int{p.Pockets[BLACK], p.Pockets[WHITE]}
+	ForEachBit(p.Promoted, func(square int) {
+		m.Promoted = SetBit(m.Promoted, square^56)
+	})
+	m.Enpassant = -1
+	if p.Enpassant >= 0 {
+		m.Enpassant = p.Enpassant ^ 56
+	}
+	m.FiftyMoveCounter, m.FullMoveCounter = p.FiftyMoveCounter, p.FullMoveCounter
+	m.Hash = m.generateZobristHash()
+	return
+}
+
+//returns the position in Forsyth-Edwards Notation
+func (p *Position) FEN() string {
+	var fen strings.Builder
+	for rank := 0; rank < 8; rank++ {
+		empty := 0
+		for square := rank * 8; square < rank*8+8; square++ {
+			piece := p.GetPieceOnSquare(square)
+			if piece < 0 {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				fen.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+			colour := WHITE
+			if CheckBit(p.Colours[BLACK], square) {
+				colour = BLACK
+			}
+			fen.WriteString(pieceNamesDisplay[colour][piece])
+			if CheckBit(p.Promoted, square) {
+				fen.WriteByte('~')
+			}
+		}
+		if empty > 0 {
+			fen.WriteString(strconv.Itoa(empty))
+		}
+		if rank < 7 {
+			fen.WriteByte('/')
+		}
+	}
+
+	if p.Variant == CRAZYHOUSE {
+		fen.WriteString("[" + p.pocketString() + "]")
+	}
+
+	if p.ToMove == WHITE {
+		fen.WriteString(" w ")
+	} else {
+		fen.WriteString(" b ")
+	}
+
+	castling := ""
+	for i, right := range p.castleRights() {
+		if !*right {
+			continue
+		}
+		//X-FEN: the rook's file if it isn't the outermost one
+		letter := "KQkq"[i]
+		if p.Chess960 && p.CastleRooks[i] != p.outermostRook(i/2, i%2 == 0) {
+			letter = byte('A' + File(p.CastleRooks[i]) - 1)
+			if i/2 == BLACK {
+				letter += 'a' - 'A'
+			}
+		}
+		castling += string(letter)
+	}
+	if castling == "" {
+		castling = "-"
+	}
+	fen.WriteString(castling)
+
+	if p.Enpassant >= 0 {
+		fen.WriteString(" " + SquareToAlgebraic(p.Enpassant))
+	} else {
+		fen.WriteString(" -")
+	}
+	fen.WriteString(p.variantFields())
+	fmt.Fprintf(&fen, " %d %d", p.FiftyMoveCounter, p.FullMoveCounter)
+	return fen.String()
+}
+
+func (p Position) Output() {
+	boardString := make([]string, 64)
+	for piece, board := range p.Pieces {
+		if board == 0 {
+			continue
+		}
+
+		for i := 0; i < 64; i++ {
+			if CheckBit(board, i) {
+				if CheckBit(p.Colours[WHITE], i) {
+					boardString[i] = pieceNamesDisplay[WHITE][piece]
+				} else if CheckBit(p.Colours[BLACK], i) {
+					boardString[i] = pieceNamesDisplay[BLACK][piece]
+				}
+			}
+		}
+	}
+
+	for i, piece := range boardString {
+		if i%8 == 0 {
+			fmt.Print("+---+---+---+---+---+---+---+---+\n|")
+		}
+		if piece == "" {
+			fmt.Print("   |")
+		} else {
+			fmt.Print(" ", piece, " |")
+		}
+		if i%8 == 7 {
+			switch i / 8 {
+			case 0:
+				fmt.Println(" HASH:", p.Hash)
+			case 1:
+				fmt.Println(" Turn", p.FullMoveCounter, "| 50 Move Counter:", p.FiftyMoveCounter)
+			case 2:
+				if p.ToMove == WHITE {
+					fmt.Println(" White to move.")
+				} else {
+					fmt.Println(" Black to move.")
+				}
+			case 3:
+				if p.CastleWK && p.CastleWQ {
+					fmt.Println(" White can castle to both sides.")
+				} else if p.CastleWK {
+					fmt.Println(" White can castle kingside.")
+				} else if p.CastleWQ {
+					fmt.Println(" White can castle queenside.")
+				} else {
+					fmt.Println(" White can no longer castle.")
+				}
+			case 4:
+				if p.CastleBK && p.CastleBQ {
+					fmt.Println(" Black can castle to both sides.")
+				} else if p.CastleBK {
+					fmt.Println(" Black can castle kingside.")
+				} else if p.CastleBQ {
+					fmt.Println(" Black can castle queenside.")
+				} else {
+					fmt.Println(" Black can no longer castle.")
+				}
+			case 5:
+				if p.Enpassant >= 0 {
+					fmt.Println(" Enpassant square: ", SquareToAlgebraic(p.Enpassant))
+				} else {
+					fmt.Print("\n")
+				}
+			case 6:
+				if p.ToMove == WHITE && p.InCheck() {
+					fmt.Println(" White king is in check!")
+				} else if p.ToMove == BLACK && p.InCheck() {
+					fmt.Println(" Black king is in check!")
+				} else {
+					fmt.Print("\n")
+				}
+			case 7:
+				if p.Variant == THREECHECK {
+					fmt.Printf(" Variant: %s. Checks given: %d+%d\n", p.Variant, p.Checks[WHITE], p.Checks[BLACK])
+				} else if p.Variant == CRAZYHOUSE {
+					fmt.Printf(" Variant: %s. Pockets: [%s]\n", p.Variant, p.pocketString())
+				} else if p.Variant != STANDARD {
+					fmt.Println(" Variant:", p.Variant)
+				} else {
+					fmt.Print("\n")
+				}
+			default:
+				fmt.Print("\n")
+			}
+		}
+	}
+	fmt.Print("+---+---+---+---+---+---+---+---+\n")
+}
+
+//tests whether a space is attacked by the provided colour player
+func (p *Position) IsSquareAttacked(square, col int) bool {
+	//check non-sliding pieces first
+	if PawnAttacks[Opponent(col)][square]&p.Pieces[PAWN]&p.Colours[col] != 0 || knightMoves[square]&p.Pieces[KNIGHT]&p.Colours[col] != 0 || kingMoves[square]&p.Pieces[KING]&p.Colours[col] != 0 {
+		return true
+	}
+
+	//diagonals
+	diagonalsSliders := p.Colours[col] & (p.Pieces[BISHOP] | p.Pieces[QUEEN])
+	for dir := UPLEFT; dir <= DOWNLEFT; dir += 2 {
+		rayMoves := slidingMoves[dir][square]
+		if rayMoves&diagonalsSliders != 0 { //if bishop or queen is even on diagonal
+			var endSquare int
+			if dir <= UPRIGHT {
+				endSquare = RightBit(rayMoves & (p.Colours[WHITE] | p.Colours[BLACK]))
+			} else {
+				endSquare = LeftBit(rayMoves & (p.Colours[WHITE] | p.Colours[BLACK]))
+			}
+
+			if CheckBit(diagonalsSliders, endSquare) {
+				return true
+			}
+		}
+	}
+
+	//rank and files
+	horizontalSliders := p.Colours[col] & (p.Pieces[ROOK] | p.Pieces[QUEEN])
+	for dir := LEFT; dir <= DOWN; dir += 2 {
+		rayMoves := slidingMoves[dir][square]
+		if rayMoves&horizontalSliders != 0 { //if rook or queen is even on diagonal
+			var endSquare int
+			if dir <= UPRIGHT {
+				endSquare = RightBit(rayMoves & (p.Colours[WHITE] | p.Colours[BLACK]))
+			} else {
+				endSquare = LeftBit(rayMoves & (p.Colours[WHITE] | p.Colours[BLACK]))
+			}
+
+			if CheckBit(horizontalSliders, endSquare) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (p *Position) GetPieceOnSquare(square int) int {
+	for i := PAWN; i <= KING; i++ {
+		if CheckBit(p.Pieces[i], square) {
+			return i
+		}
+	}
+	return -1
+}
+
+//returns to square the king is on
+func (p *Position) GetKingSquare(col int) int {
+	return LeftBit(p.Pieces[KING] & p.Colours[col])
+}
+
+//reports whether the side to move is in check. kings are like any other piece in antichess, so it never is.
+func (p *Position) InCheck() bool {
+	if p.Variant == ANTICHESS || p.Pieces[KING]&p.Colours[p.ToMove] == 0 {
+		return false
+	}
+	return p.kingAttacked(p.GetKingSquare(p.ToMove))
+}
+
+//removes a piece from the position, updating bitboards and stuff as appropriate
+func (p *Position) RemovePiece(colour, piece, square int) {
+	p.Colours[colour] = ClearBit(p.Colours[colour], square)
+	p.Pieces[piece] = ClearBit(p.Pieces[piece], square)
+	p.Hash ^= zobrist.pieces[colour][piece][square]
+	p.recordChange(PieceChange(square | piece<<6 | colour<<9))
+}
+
+func (p *Position) AddPiece(colour, piece, square int) {
+	p.Colours[colour] = SetBit(p.Colours[colour], square)
+	p.Pieces[piece] = SetBit(p.Pieces[piece], square)
+	p.Hash ^= zobrist.pieces[colour][piece][square]
+	p.recordChange(PieceChange(square|piece<<6|colour<<9) | pieceAdded)
+}
+
+//a move makes at most MAXPIECECHANGES, so there's always room for them. pieces added and removed outside
+//of DoMove (setting up a position, say) wrap around instead of being checked for, which keeps AddPiece
+//and RemovePiece small enough to be inlined.
+func (p *Position) recordChange(c PieceChange) {
+	p.changes[p.numChanges&15] = c
+	p.numChanges++
+}
+
+//the pieces the last move put on or took off the board, in the order it happened. for keeping things
+//that follow the pieces (nnue accumulators) up to date without going over the whole board.
+func (p *Position) PieceChanges() []PieceChange {
+	return p.changes[:min(p.numChanges, MAXPIECECHANGES)]
+}
+
+func (p *Position) DoMove(m Move) {
+	p.numChanges = 0
+	p.FiftyMoveCounter++
+	if p.ToMove == BLACK {
+		p.FullMoveCounter++
+	}
+
+	//update bitboards
+	if m.CastleK() || m.CastleQ() {
+		p.castle(m)
+	} else if m.Drop() {
+		p.drop(m)
+	} else {
+		p.movePiece(m)
+	}
+
+	if m.Piece() == PAWN {
+		p.FiftyMoveCounter = 0
+	}
+
+	//update enpassant square
+	if p.Enpassant != -1 {
+		p.Hash ^= zobrist.enpassant[File(p.Enpassant)-1]
+	}
+	if m.PawnJump() {
+		if p.ToMove == WHITE {
+			p.Enpassant = m.From() - 8
+		} else {
+			p.Enpassant = m.From() + 8
+		}
+		p.Hash ^= zobrist.enpassant[File(p.Enpassant)-1]
+	} else {
+		p.Enpassant = -1
+	}
+
+	//update castling availability. moving the king (or having it blown up) loses both of its side's rights,
+	//and a rook leaving its square (moved, captured or blown up) loses that rook's
+	for i, right := range p.castleRights() {
+		if !*right {
+			continue
+		}
+		if i/2 == p.ToMove && m.Piece() == KING || p.Pieces[KING]&p.Colours[i/2] == 0 || !CheckBit(p.Colours[i/2]&p.Pieces[ROOK], p.CastleRooks[i]) {
+			*right = false
+			p.Hash ^= zobrist.castle[i]
+		}
+	}
+
+	p.MoveHistory = append(p.MoveHistory, m)
+	p.ToMove = Opponent(p.ToMove)
+	p.Hash ^= zobrist.black
+
+	if p.Variant != STANDARD {
+		p.variantMove(m)
+	}
+}
+
+//moves the piece for a move that isn't castling, taking anything it captures
+func (p *Position) movePiece(m Move) {
+	p.RemovePiece(p.ToMove, m.Piece(), m.From())
+
+	if m.Capture() {
+		p.FiftyMoveCounter = 0
+		if m.To() == p.Enpassant {
+			var captureSquare int
+			if p.ToMove == WHITE {
+				captureSquare = p.Enpassant + 8
+			} else {
+				captureSquare = p.Enpassant - 8
+			}
+			p.RemovePiece(Opponent(p.ToMove), PAWN, captureSquare)
+		} else {
+			p.RemovePiece(Opponent(p.ToMove), m.CapturePiece(), m.To())
+		}
+	}
+
+	if m.Promote() {
+		p.AddPiece(p.ToMove, m.PromotedPiece(), m.To())
+	} else {
+		p.Pieces[m.Piece()] = SetBit(p.Pieces[m.Piece()], m.To())
+		p.AddPiece(p.ToMove, m.Piece(), m.To())
+	}
+
+	if p.Variant == ATOMIC && m.Capture() {
+		p.explode(m.To())
+	}
+}
+
+//moves the king and rook for castling. both come off before either goes back on, since in chess960 each
+//can land where the other started.
+func (p *Position) castle(m Move) {
+	i := p.ToMove * 2
+	if m.CastleQ() {
+		i++
+	}
+	p.RemovePiece(p.ToMove, KING, m.From())
+	p.RemovePiece(p.ToMove, ROOK, p.CastleRooks[i])
+	p.AddPiece(p.ToMove, KING, castleKingTargets[i])
+	p.AddPiece(p.ToMove, ROOK, castleRookTargets[i])
+}
+
+func (p *Position) generateZobristHash() (hash uint64) {
+	//pieces
+	for colour := WHITE; colour <= BLACK; colour++ {
+		for piece := PAWN; piece <= KING; piece++ {
+			ForEachBit(p.Colours[colour]&p.Pieces[piece], func(square int) {
+				hash ^= zobrist.pieces[colour][piece][square]
+			})
+		}
+	}
+
+	//castling
+	if p.CastleWK {
+		hash ^= zobrist.castle[0]
+	}
+	if p.CastleWQ {
+		hash ^= zobrist.castle[1]
+	}
+	if p.CastleBK {
+		hash ^= zobrist.castle[2]
+	}
+	if p.CastleBQ {
+		hash ^= zobrist.castle[3]
+	}
+
+	//enpassant
+	if p.Enpassant >= 0 {
+		hash ^= zobrist.enpassant[File(p.Enpassant)-1]
+	}
+
+	//turn
+	if p.ToMove == BLACK {
+		hash ^= zobrist.black
+	}
+
+	hash ^= p.variantHash()
+
+	return
+}
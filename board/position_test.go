@@ -0,0 +1,108 @@
+package board
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestFEN(t *testing.T) {
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w KQkq c6 0 2",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 b - - 12 40",
+		"r3k3/8/8/8/8/8/8/4K2R w Kq - 0 1",
+	}
+	for _, fen := range fens {
+		pos := NewPosition(fen)
+		if got := pos.FEN(); got != fen {
+			t.Errorf("Expected %s, got %s", fen, got)
+		}
+	}
+}
+
+//mirrored positions should have the same perft counts as the originals
+func TestMirror(t *testing.T) {
+	tests, err := loadPerftSuite("../test/perftSuite.epd")
+	if err != nil {
+		t.Fatal("Could not open perft test suite:", err)
+	}
+	for _, perft := range tests {
+		pos := NewPosition(perft.fen)
+		mirror := pos.Mirror()
+		if twice := mirror.Mirror(); twice.FEN() != pos.FEN() || twice.Hash != pos.Hash {
+			t.Errorf("%s mirrored twice is %s", perft.fen, twice.FEN())
+		}
+		for i, val := range perft.vals {
+			if perft.depths[i] > 3 {
+				break
+			}
+			if n := mirror.Perft(perft.depths[i], nil); n != val {
+				t.Errorf("%s mirrored (%s), depth %d. Expected %d, got %d", perft.fen, mirror.FEN(), perft.depths[i], val, n)
+			}
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for game := 0; game < 20; game++ {
+		pos := NewPosition("startpos")
+		for ply := 0; ply < 80; ply++ {
+			moves, _ := Movegen(&pos)
+			if len(moves) == 0 {
+				break
+			}
+			pos.DoMove(moves[rng.Intn(len(moves))])
+			mirror := pos.Mirror()
+			if a, b := pos.Perft(2, nil), mirror.Perft(2, nil); a != b {
+				t.Fatalf("%s: perft 2 gives %d, mirrored %d", pos.FEN(), a, b)
+			}
+		}
+	}
+}
+
+func TestChess960(t *testing.T) {
+	//Shredder-FEN and X-FEN for the same position
+	shredder := NewPosition("bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9")
+	xfen := NewPosition("bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w KQkq - 2 9")
+	if !shredder.Chess960 || shredder.Hash != xfen.Hash || shredder.CastleRooks != xfen.CastleRooks {
+		t.Errorf("Shredder-FEN and X-FEN read differently")
+	}
+	if inner := NewPosition("4k3/8/8/8/8/8/8/RR1K4 w B - 0 1"); inner.FEN() != "4k3/8/8/8/8/8/8/RR1K4 w B - 0 1" {
+		t.Errorf("X-FEN for an inner rook written as %s", inner.FEN())
+	}
+
+	tests := []struct {
+		fen, uci, after string
+	}{
+		//the king and rook swap places
+		{"4k3/8/8/8/8/8/8/1R3KR1 w GB - 0 1", "f1g1", "4k3/8/8/8/8/8/8/1R3RK1 b - - 1 1"},
+		{"4k3/8/8/8/8/8/8/1R3KR1 w GB - 0 1", "f1b1", "4k3/8/8/8/8/8/8/2KR2R1 b - - 1 1"},
+		//king stays put
+		{"1r4kr/8/8/8/8/8/8/4K3 b Kk - 0 1", "g8h8", "1r3rk1/8/8/8/8/8/8/4K3 w - - 1 2"},
+		//the rook on b1 would block the attack on c1 if it stayed
+		{"4k3/8/8/8/8/8/8/qR1K4 w B - 0 1", "d1b1", ""},
+		//normal chess still castles with the king's move
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", "r3k2r/8/8/8/8/8/8/R4RK1 b kq - 1 1"},
+	}
+	for _, test := range tests {
+		pos := NewPosition(test.fen)
+		m, ok := ParseMove(&pos, test.uci)
+		if test.after == "" {
+			if ok {
+				t.Errorf("%s: %s should be illegal", test.fen, test.uci)
+			}
+			continue
+		}
+		if !ok || !(m.CastleK() || m.CastleQ()) || m.UCIstring() != test.uci {
+			t.Errorf("%s: %s isn't castling", test.fen, test.uci)
+			continue
+		}
+		pos.DoMove(m)
+		if pos.FEN() != test.after {
+			t.Errorf("%s after %s: expected %s, got %s", test.fen, test.uci, test.after, pos.FEN())
+		}
+		if pos.Hash != pos.generateZobristHash() {
+			t.Errorf("%s after %s: hash not updated properly", test.fen, test.uci)
+		}
+	}
+}
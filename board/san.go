@@ -0,0 +1,85 @@
+package board
+
+import "strings"
+
+//SAN gives the move in standard algebraic notation (Nf3, exd5, O-O, e8=Q+, N@f3), as used in PGN. the move
+//must be legal in the position.
+func (p *Position) SAN(m Move) (s string) {
+	if m.CastleK() {
+		s = "O-O"
+	} else if m.CastleQ() {
+		s = "O-O-O"
+	} else if m.Drop() {
+		s = m.String()
+	} else if m.Piece() == PAWN {
+		if m.Capture() {
+			s = SquareToAlgebraic(m.From())[:1] + "x"
+		}
+		s += SquareToAlgebraic(m.To())
+		if m.Promote() {
+			s += "=" + pieceNamesShort[m.PromotedPiece()]
+		}
+	} else {
+		s = pieceNamesShort[m.Piece()] + p.disambiguate(m)
+		if m.Capture() {
+			s += "x"
+		}
+		s += SquareToAlgebraic(m.To())
+	}
+
+	after := p.Copy()
+	after.DoMove(m)
+	if after.InCheck() {
+		if moves, _ := Movegen(&after); len(moves) == 0 {
+			return s + "#"
+		}
+		return s + "+"
+	}
+	return
+}
+
+//the from file and/or rank needed when another piece of the same kind can move to the same square
+func (p *Position) disambiguate(m Move) string {
+	moves, _ := Movegen(p)
+	var ambiguous, sameFile, sameRank bool
+	for _, other := range moves {
+		if other == m || other.Drop() || other.Piece() != m.Piece() || other.To() != m.To() {
+			continue
+		}
+		ambiguous = true
+		sameFile = sameFile || File(other.From()) == File(m.From())
+		sameRank = sameRank || Rank(other.From()) == Rank(m.From())
+	}
+	from := SquareToAlgebraic(m.From())
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return from[:1]
+	case !sameRank:
+		return from[1:]
+	}
+	return from
+}
+
+//finds the legal move in the position matching a move in SAN. check marks and annotations (!, ?) are
+//optional, castling can be written with zeros, and the = before a promotion piece can be left out.
+func ParseSAN(p *Position, s string) (m Move, ok bool) {
+	normalise := func(s string) string {
+		s = strings.TrimRight(s, "+#!?")
+		s = strings.ReplaceAll(s, "0", "O")
+		return strings.ReplaceAll(s, "=", "")
+	}
+	s = normalise(s)
+	if s == "" {
+		return
+	}
+
+	moves, _ := Movegen(p)
+	for _, lm := range moves {
+		if normalise(p.SAN(lm)) == s {
+			return lm, true
+		}
+	}
+	return
+}
@@ -0,0 +1,31 @@
+package board
+
+import "testing"
+
+func TestSAN(t *testing.T) {
+	tests := []struct {
+		fen, uci, san string
+	}{
+		{"startpos", "g1f3", "Nf3"},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "e1g1", "O-O"},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "e2a6", "Bxa6"},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "d5e6", "dxe6"},
+		{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "a1d1", "Rad1"},
+		{"4k3/8/8/R7/8/8/8/R3K3 w - - 0 1", "a1a3", "R1a3"},
+		{"4k3/8/8/8/8/8/1p6/R3K3 b - - 0 1", "b2a1q", "bxa1=Q+"},
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "a1a8", "Ra8#"},
+	}
+	for _, test := range tests {
+		pos := NewPosition(test.fen)
+		m, ok := ParseMove(&pos, test.uci)
+		if !ok {
+			t.Fatalf("%s: couldn't parse %s", test.fen, test.uci)
+		}
+		if san := pos.SAN(m); san != test.san {
+			t.Errorf("%s: expected %s for %s, got %s", test.fen, test.san, test.uci, san)
+		}
+		if parsed, ok := ParseSAN(&pos, test.san); !ok || parsed != m {
+			t.Errorf("%s: couldn't read back %s", test.fen, test.san)
+		}
+	}
+}
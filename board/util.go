@@ -1,10 +1,10 @@
-package main
+package board
 
 import "fmt"
 
 var seed uint64 = 80
 
-func algebraicToSquare(s string) int {
+func AlgebraicToSquare(s string) int {
 	if len(s) != 2 {
 		return 0
 	}
@@ -34,7 +34,7 @@ func algebraicToSquare(s string) int {
 	return square + 8*(8-int(s[1]-'0'))
 }
 
-func squareToAlgebraic(s int) string {
+func SquareToAlgebraic(s int) string {
 
 	alg := ""
 	switch s % 8 {
@@ -59,17 +59,17 @@ func squareToAlgebraic(s int) string {
 	return alg + fmt.Sprint(8-(s/8))
 }
 
-//return the rank of the square
-func rank(square int) int {
+//return the Rank of the square
+func Rank(square int) int {
 	return 8 - square/8
 }
 
-//returns the number of the file of the square
-func file(square int) int {
+//returns the number of the File of the square
+func File(square int) int {
 	return square%8 + 1
 }
 
-func opponent(col int) int {
+func Opponent(col int) int {
 	if col == WHITE {
 		return BLACK
 	}
@@ -81,7 +81,7 @@ func generateKey() uint64 {
 	return seed
 }
 
-func nps(nodes int, seconds float64) string {
+func NPS(nodes int, seconds float64) string {
 	nps := float64(nodes) / seconds
 	if nps > 1000000 {
 		return fmt.Sprintf("%.2f Mnps", nps/1000000)
@@ -0,0 +1,289 @@
+package board
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//Variant is a set of rules the game is played by instead of the standard ones. the rules live in the
+//hooks below: how the game can end, the extra state positions keep, what goes in the hash and how the
+//extra state is written in FENs. the zero value is standard chess.
+type Variant int
+
+const (
+	STANDARD      Variant = iota
+	THREECHECK            //giving the third check wins
+	KINGOFTHEHILL         //getting the king to one of the four centre squares wins
+	ANTICHESS             //captures are compulsory, and losing all your pieces or having no moves wins
+	ATOMIC                //captures explode everything around them but pawns. blowing up the king wins
+	CRAZYHOUSE            //captured pieces change sides, and can be dropped back on the board instead of a move
+
+	NUMVARIANTS
+)
+
+//names of the variants, as UCI_Variant and other engines have them
+var VariantNames = [NUMVARIANTS]string{"chess", "3check", "kingofthehill", "antichess", "atomic", "crazyhouse"}
+
+func (v Variant) String() string {
+	return VariantNames[v]
+}
+
+//finds a variant by name
+func ParseVariant(name string) (v Variant, ok bool) {
+	for v := STANDARD; v < NUMVARIANTS; v++ {
+		if strings.EqualFold(name, VariantNames[v]) {
+			return v, true
+		}
+	}
+	return STANDARD, false
+}
+
+//Outcome is how the game has ended, for the side to move
+type Outcome int
+
+const (
+	Ongoing Outcome = iota
+	Won
+	Lost
+	Drawn
+)
+
+//checks needed to win at three-check
+const WINNINGCHECKS int = 3
+
+//most of one piece a crazyhouse pocket can hold: every pawn in the game
+const MAXPOCKET int = 16
+
+//d5, e5, d4 and e4: the hill in king of the hill
+const hill uint64 = 1<<27 | 1<<28 | 1<<35 | 1<<36
+
+//how many king moves the square is from the hill
+func HillDistance(square int) int {
+	file := max(4-File(square), File(square)-5, 0)
+	rank := max(4-Rank(square), Rank(square)-5, 0)
+	return max(file, rank)
+}
+
+//changes the rules the position is played by
+func (p *Position) SetVariant(v Variant) {
+	p.Variant = v
+	if v == ANTICHESS { //no castling, whatever the FEN said
+		p.CastleWK, p.CastleWQ, p.CastleBK, p.CastleBQ = false, false, false, false
+	}
+	p.Hash = p.generateZobristHash()
+}
+
+//the result of the game for the side to move once it has no legal moves, which is how every game ends:
+//checkmate and stalemate, or whatever the variant has ended it with.
+func (p *Position) Outcome() Outcome {
+	if o := p.variantOutcome(); o != Ongoing {
+		return o
+	}
+	switch {
+	case p.Variant == ANTICHESS: //out of pieces or stuck, which is the point
+		return Won
+	case p.InCheck():
+		return Lost
+	}
+	return Drawn
+}
+
+//whether the variant's own rules have ended the game. the move that ends it has always been made by the
+//side not to move, so there are no moves left to generate.
+func (p *Position) variantOutcome() Outcome {
+	switch p.Variant {
+	case THREECHECK:
+		if p.Checks[Opponent(p.ToMove)] >= WINNINGCHECKS {
+			return Lost
+		}
+	case KINGOFTHEHILL:
+		if p.Pieces[KING]&p.Colours[Opponent(p.ToMove)]&hill != 0 {
+			return Lost
+		}
+	case ATOMIC:
+		if p.Pieces[KING]&p.Colours[p.ToMove] == 0 {
+			return Lost
+		}
+	}
+	return Ongoing
+}
+
+//whether the side to move's king would be in check on the square. in atomic, kings next to each other
+//can't be: taking one would blow up the other.
+func (p *Position) kingAttacked(square int) bool {
+	if p.Variant == ATOMIC && kingMoves[square]&p.Pieces[KING]&p.Colours[Opponent(p.ToMove)] != 0 {
+		return false
+	}
+	return p.IsSquareAttacked(square, Opponent(p.ToMove))
+}
+
+//atomic captures blow up the capturing piece and everything around it apart from pawns
+func (p *Position) explode(square int) {
+	blast := SetBit(kingMoves[square]&^p.Pieces[PAWN], square) & (p.Colours[WHITE] | p.Colours[BLACK])
+	ForEachBit(blast, func(sq int) {
+		colour := WHITE
+		if CheckBit(p.Colours[BLACK], sq) {
+			colour = BLACK
+		}
+		p.RemovePiece(colour, p.GetPieceOnSquare(sq), sq)
+	})
+}
+
+//whether a move is legal in atomic: kings can't capture, a move can't blow up its own king, and one that
+//blows up the other king wins even if it leaves its own in check
+func (p *Position) atomicLegal(m Move) bool {
+	us, them := p.ToMove, Opponent(p.ToMove)
+	if m.Piece() == KING && m.Capture() {
+		return false
+	}
+
+	colours := p.Colours
+	colours[us] = SetBit(ClearBit(colours[us], m.From()), m.To())
+	king := p.GetKingSquare(us)
+	if m.Piece() == KING {
+		king = m.To()
+	}
+	if m.Capture() {
+		captured := m.To()
+		if m.Piece() == PAWN && m.To() == p.Enpassant {
+			captured = m.To() + 8 - 16*us
+		}
+		colours[them] = ClearBit(colours[them], captured)
+
+		blast := SetBit(kingMoves[m.To()]&^p.Pieces[PAWN], m.To())
+		if CheckBit(blast, king) {
+			return false
+		} else if blast&p.Pieces[KING]&colours[them] != 0 {
+			return true
+		}
+		colours[WHITE] &^= blast
+		colours[BLACK] &^= blast
+	}
+
+	//check the king with the move made on the colour bitboards, which is all IsSquareAttacked needs
+	saved := p.Colours
+	p.Colours = colours
+	attacked := p.kingAttacked(king)
+	p.Colours = saved
+	return !attacked
+}
+
+//keeps the variant's state up to date after a move. ToMove has already changed to the side that didn't
+//make it.
+func (p *Position) variantMove(m Move) {
+	mover := Opponent(p.ToMove)
+	switch p.Variant {
+	case THREECHECK:
+		if p.InCheck() {
+			p.Hash ^= zobrist.checks[mover][p.Checks[mover]]
+			p.Checks[mover]++
+			p.Hash ^= zobrist.checks[mover][p.Checks[mover]]
+		}
+	case CRAZYHOUSE:
+		if m.Drop() {
+			return
+		}
+		//captured pieces go in the capturer's pocket, promoted ones as pawns
+		if m.Capture() {
+			captured := m.CapturePiece()
+			if CheckBit(p.Promoted, m.To()) {
+				captured = PAWN
+				p.Promoted = ClearBit(p.Promoted, m.To())
+			}
+			p.changePocket(mover, captured, 1)
+		}
+		if CheckBit(p.Promoted, m.From()) {
+			p.Promoted = SetBit(ClearBit(p.Promoted, m.From()), m.To())
+		} else if m.Promote() {
+			p.Promoted = SetBit(p.Promoted, m.To())
+		}
+	}
+}
+
+//puts a piece from the pocket on the board
+func (p *Position) drop(m Move) {
+	p.changePocket(p.ToMove, m.Piece(), -1)
+	p.AddPiece(p.ToMove, m.Piece(), m.To())
+}
+
+//adds pieces to a pocket, or takes them out
+func (p *Position) changePocket(colour, piece, change int) {
+	p.Hash ^= zobrist.pockets[colour][piece][p.Pockets[colour][piece]]
+	p.Pockets[colour][piece] += change
+	p.Hash ^= zobrist.pockets[colour][piece][p.Pockets[colour][piece]]
+}
+
+//the part of the hash for the variant and its state
+func (p *Position) variantHash() (hash uint64) {
+	hash = zobrist.variant[p.Variant]
+	switch p.Variant {
+	case THREECHECK:
+		hash ^= zobrist.checks[WHITE][p.Checks[WHITE]] ^ zobrist.checks[BLACK][p.Checks[BLACK]]
+	case CRAZYHOUSE:
+		for colour := WHITE; colour <= BLACK; colour++ {
+			for piece, count := range p.Pockets[colour] {
+				hash ^= zobrist.pockets[colour][piece][count]
+			}
+		}
+	}
+	return
+}
+
+//the pieces in the pockets the way FENs have them: white's then black's, in capitals and lower case
+func (p *Position) pocketString() (s string) {
+	for colour := WHITE; colour <= BLACK; colour++ {
+		for piece := QUEEN; piece >= PAWN; piece-- {
+			s += strings.Repeat(pieceNamesDisplay[colour][piece], p.Pockets[colour][piece])
+		}
+	}
+	return
+}
+
+//takes the variant's fields out of a FEN (already split up), returning the standard ones. three-check
+//counters come as the checks each side has left after the en passant square (3+3), or the checks each
+//side has given at the end (+0+0), the way lichess writes them. either makes it a three-check position.
+//crazyhouse pockets are in brackets after the board (RNBQKBNR[Qp]), which makes it a crazyhouse position.
+func (p *Position) parseVariantFields(fields []string) (standard []string) {
+	for i, field := range fields {
+		if board, pocket, ok := strings.Cut(field, "["); i == 0 && ok {
+			for _, ch := range strings.TrimSuffix(pocket, "]") {
+				if piece, ok := displayLookup[ch]; ok && piece.piece != KING && p.Pockets[piece.colour][piece.piece] < MAXPOCKET {
+					p.Pockets[piece.colour][piece.piece]++
+				}
+			}
+			p.Variant = CRAZYHOUSE
+			standard = append(standard, board)
+			continue
+		}
+		if i < 4 || !strings.Contains(field, "+") {
+			standard = append(standard, field)
+			continue
+		}
+		given := strings.HasPrefix(field, "+")
+		counts := strings.Split(strings.TrimPrefix(field, "+"), "+")
+		if len(counts) != 2 {
+			continue
+		}
+		for colour, count := range counts {
+			n, err := strconv.Atoi(count)
+			if err != nil {
+				continue
+			}
+			if !given {
+				n = WINNINGCHECKS - n
+			}
+			p.Checks[colour] = min(max(n, 0), WINNINGCHECKS)
+		}
+		p.Variant = THREECHECK
+	}
+	return
+}
+
+//the variant's FEN fields, which go after the en passant square
+func (p *Position) variantFields() string {
+	if p.Variant == THREECHECK {
+		return fmt.Sprintf(" %d+%d", WINNINGCHECKS-p.Checks[WHITE], WINNINGCHECKS-p.Checks[BLACK])
+	}
+	return ""
+}
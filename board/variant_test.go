@@ -0,0 +1,306 @@
+package board
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestThreeCheckFEN(t *testing.T) {
+	fen := "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 2+3 0 2"
+	for _, f := range []string{fen, "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2 +1+0"} {
+		pos, err := ParseFEN(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pos.Variant != THREECHECK || pos.Checks != [2]int{1, 0} || pos.FullMoveCounter != 2 {
+			t.Errorf("%s: read as %s with checks %v, move %d", f, pos.Variant, pos.Checks, pos.FullMoveCounter)
+		}
+		if pos.FEN() != fen {
+			t.Errorf("%s: written as %s", f, pos.FEN())
+		}
+	}
+
+	standard := NewPosition("rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2")
+	pos := standard
+	pos.SetVariant(THREECHECK)
+	if pos.Hash == standard.Hash || pos.Hash == NewPosition(fen).Hash {
+		t.Error("Variant and checks aren't in the hash")
+	}
+}
+
+//perft by the standard rules, cutting the game off by hand wherever the variant says it's over
+func referencePerft(p Position, n int, checks [2]int, variant Variant) (nodes int) {
+	list, _ := Movegen(&p)
+	for _, m := range list {
+		next, nextChecks := p, checks
+		next.DoMove(m)
+		over := false
+		switch variant {
+		case THREECHECK:
+			if next.InCheck() {
+				nextChecks[p.ToMove]++
+			}
+			over = nextChecks[p.ToMove] == WINNINGCHECKS
+		case KINGOFTHEHILL:
+			king := SquareToAlgebraic(next.GetKingSquare(p.ToMove))
+			over = king == "d4" || king == "e4" || king == "d5" || king == "e5"
+		}
+		if n == 1 {
+			nodes++
+		} else if !over {
+			nodes += referencePerft(next, n-1, nextChecks, variant)
+		}
+	}
+	return
+}
+
+func TestVariantPerft(t *testing.T) {
+	for _, v := range []Variant{THREECHECK, KINGOFTHEHILL} {
+		start := NewPosition("")
+		start.SetVariant(v)
+		if nodes := start.Perft(4, nil); nodes != 197281 {
+			t.Errorf("%s start position: perft 4 is %d, expected 197281", v, nodes)
+		}
+	}
+
+	tests := []struct {
+		fen     string
+		variant Variant
+		depth   int
+	}{
+		{"4k3/8/8/8/8/8/8/4K2R w K - 1+2 0 1", THREECHECK, 4},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 1+1 0 1", THREECHECK, 3},
+		{"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 2+1 0 1", THREECHECK, 4},
+		{"8/8/4k3/8/8/3K4/8/8 w - - 0 1", KINGOFTHEHILL, 4},
+		{"r3k2r/p1pp1pb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R b KQkq - 0 1", KINGOFTHEHILL, 3},
+	}
+	for _, test := range tests {
+		pos := NewPosition(test.fen)
+		pos.SetVariant(test.variant)
+		standard := NewPosition(test.fen)
+		standard.SetVariant(STANDARD)
+		expected := referencePerft(standard, test.depth, pos.Checks, test.variant)
+		if nodes := pos.Perft(test.depth, nil); nodes != expected {
+			t.Errorf("%s (%s): perft %d is %d, expected %d", test.fen, test.variant, test.depth, nodes, expected)
+		}
+	}
+}
+
+//the hash kept up to date move by move should match one made from scratch, and the pieces each move
+//says it added and removed should take the board from before the move to after it
+func TestVariantHash(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for v := STANDARD; v < NUMVARIANTS; v++ {
+		for game := 0; game < 20; game++ {
+			pos := NewPosition("")
+			pos.SetVariant(v)
+			for ply := 0; ply < 200; ply++ {
+				moves, _ := Movegen(&pos)
+				if len(moves) == 0 {
+					break
+				}
+				colours, pieces := pos.Colours, pos.Pieces
+				pos.DoMove(moves[rng.Intn(len(moves))])
+				if pos.Hash != pos.generateZobristHash() {
+					t.Fatalf("%s: hash is wrong after %v", v, pos.MoveHistory)
+				}
+				for _, c := range pos.PieceChanges() {
+					if c.Added() {
+						colours[c.Colour()], pieces[c.Piece()] = SetBit(colours[c.Colour()], c.Square()), SetBit(pieces[c.Piece()], c.Square())
+					} else {
+						colours[c.Colour()], pieces[c.Piece()] = ClearBit(colours[c.Colour()], c.Square()), ClearBit(pieces[c.Piece()], c.Square())
+					}
+				}
+				if colours != pos.Colours || pieces != pos.Pieces {
+					t.Fatalf("%s: piece changes don't add up after %v", v, pos.MoveHistory)
+				}
+			}
+		}
+	}
+}
+
+func TestAntichess(t *testing.T) {
+	tests := []struct {
+		fen   string
+		nodes []int //by depth, from 1
+	}{
+		//the published numbers for the start position
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1", []int{20, 400, 8067, 153299, 2732672}},
+		//forced captures until black runs out of pieces, which ends the game
+		{"8/1p6/8/8/8/8/P7/8 w - - 0 1", []int{2, 4, 4, 3, 1, 0}},
+		//promoting to a king, and moving a lone king freely
+		{"8/P7/8/8/8/8/8/7k w - - 0 1", []int{5, 15}},
+	}
+	for _, test := range tests {
+		pos := NewPosition(test.fen)
+		pos.SetVariant(ANTICHESS)
+		for depth, expected := range test.nodes {
+			if nodes := pos.Perft(depth+1, nil); nodes != expected {
+				t.Errorf("%s: perft %d is %d, expected %d", test.fen, depth+1, nodes, expected)
+			}
+		}
+	}
+
+	//castling rights from the start position's FEN go
+	pos := NewPosition("")
+	pos.SetVariant(ANTICHESS)
+	if pos.CastleWK || pos.CastleBQ || pos.InCheck() {
+		t.Error("Antichess start position has castling or check")
+	}
+
+	//positions without kings are fine, but only in antichess
+	if _, err := ParseVariantFEN("8/8/8/8/8/8/p7/R7 w - - 0 1", ANTICHESS); err != nil {
+		t.Error(err)
+	}
+	if _, err := ParseFEN("8/8/8/8/8/8/p7/R7 w - - 0 1"); err == nil {
+		t.Error("Standard position without kings was read")
+	}
+
+	//losing every piece wins
+	pos = NewPosition("8/8/8/8/8/8/1p6/8 w - - 0 1")
+	pos.SetVariant(ANTICHESS)
+	if moves, _ := Movegen(&pos); len(moves) != 0 || pos.Outcome() != Won {
+		t.Errorf("White has no pieces, but has %d moves and outcome %d", len(moves), pos.Outcome())
+	}
+}
+
+func TestAtomic(t *testing.T) {
+	tests := []struct {
+		fen   string
+		nodes []int //by depth, from 1
+	}{
+		//published numbers
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", []int{20, 400, 8902, 197326, 4864979}},
+		{"rn2kb1r/1pp1p2p/p2q1pp1/3P4/2P3b1/4PN2/PP3PPP/R2QKB1R b KQkq - 0 1", []int{40, 1238, 45237, 1434825}},
+		{"rn1qkb1r/p5pp/2p5/3p4/N3P3/5P2/PPP4P/R1BQK3 w Qkq - 0 1", []int{28, 833, 23353, 714499}},
+		//not published: chess960 castling next to the other king, through squares the castling rook
+		//shields. the numbers are our own, to catch changes to the castling rules.
+		{"8/8/8/8/8/8/2k5/rR4KR w KQ - 0 1", []int{18, 180, 4364, 61401, 1603055}},
+	}
+	for _, test := range tests {
+		pos := NewPosition(test.fen)
+		pos.SetVariant(ATOMIC)
+		for depth, expected := range test.nodes {
+			if nodes := pos.Perft(depth+1, nil); nodes != expected {
+				t.Errorf("%s: perft %d is %d, expected %d", test.fen, depth+1, nodes, expected)
+			}
+		}
+	}
+
+	//Nxf8 blows up the knight, the bishop, the king and the knight beside it, but not the pawns
+	pos := NewPosition("rnbqkbnr/pppNpppp/8/8/8/8/PPPPPPPP/R1BQKBNR w KQkq - 0 1")
+	pos.SetVariant(ATOMIC)
+	m, ok := ParseMove(&pos, "d7f8")
+	if !ok {
+		t.Fatal("Nxf8 should be legal")
+	}
+	pos.DoMove(m)
+	if pos.FEN() != "rnbq3r/ppp1pppp/8/8/8/8/PPPPPPPP/R1BQKBNR b KQ - 0 1" || pos.Outcome() != Lost {
+		t.Errorf("Explosion left %s with outcome %d", pos.FEN(), pos.Outcome())
+	}
+
+	//the biggest explosion there is: every square around the capture has a piece on it
+	pos = NewPosition("k7/8/3nnn2/3nbn2/3nnn2/5N2/8/K7 w - - 0 1")
+	pos.SetVariant(ATOMIC)
+	m, _ = ParseMove(&pos, "f3e5")
+	pos.DoMove(m)
+	if changes := pos.PieceChanges(); len(changes) != MAXPIECECHANGES || pos.FEN() != "k7/8/8/8/8/8/8/K7 b - - 0 1" {
+		t.Errorf("Explosion left %s after %d piece changes", pos.FEN(), len(changes))
+	}
+
+	//kings can't capture, even to get out of check
+	pos = NewPosition("8/8/8/8/8/8/4p3/3K3k w - - 0 1")
+	pos.SetVariant(ATOMIC)
+	if _, ok := ParseMove(&pos, "d1e2"); ok || !pos.InCheck() {
+		t.Error("King captured, or wasn't in check from the pawn")
+	}
+
+	//kings side by side can't be in check
+	pos = NewPosition("8/8/8/8/8/8/3k4/r2K4 w - - 0 1")
+	pos.SetVariant(ATOMIC)
+	if pos.InCheck() {
+		t.Error("King next to the other king is in check")
+	}
+	if _, err := ParseVariantFEN("8/8/8/3kK3/8/8/8/8 w - - 0 1", ATOMIC); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCrazyhouse(t *testing.T) {
+	tests := []struct {
+		fen   string
+		nodes []int //by depth, from 1
+	}{
+		//published numbers
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1", []int{20, 400, 8902, 197281, 4888832}},
+		{"2k5/8/8/8/8/8/8/4K3[QRBNPqrbnp] w - - 0 1", []int{301, 75353}},
+	}
+	for _, test := range tests {
+		pos := NewPosition(test.fen)
+		if pos.Variant != CRAZYHOUSE || pos.FEN() != test.fen {
+			t.Errorf("%s: read as %s, %s", test.fen, pos.Variant, pos.FEN())
+		}
+		for depth, expected := range test.nodes {
+			if nodes := pos.Perft(depth+1, nil); nodes != expected {
+				t.Errorf("%s: perft %d is %d, expected %d", test.fen, depth+1, nodes, expected)
+			}
+		}
+	}
+
+	//a promoted queen goes back in the pocket as a pawn, then gets dropped
+	pos := NewPosition("1r2k3/1Q~6/8/8/8/8/8/4K3[] b - - 0 1")
+	for _, move := range []string{"b8b7", "e1d2", "P@e3"} {
+		m, ok := ParseMove(&pos, move)
+		if !ok {
+			t.Fatalf("%s should be legal in %s", move, pos.FEN())
+		}
+		if move == "P@e3" && (m.UCIstring() != "P@e3" || pos.SAN(m) != "P@e3+") {
+			t.Errorf("Drop written as %s, %s", m.UCIstring(), pos.SAN(m))
+		}
+		pos.DoMove(m)
+	}
+	if pos.FEN() != "4k3/1r6/8/8/8/4p3/3K4/8[] w - - 0 3" {
+		t.Errorf("Pockets wrong after capture and drop: %s", pos.FEN())
+	}
+
+	//there are only 16 pawns to fill a pocket with
+	full := "4k3/8/8/8/8/8/8/4K3[" + strings.Repeat("P", MAXPOCKET) + "] w - - 0 1"
+	if _, err := ParseFEN(full); err != nil {
+		t.Error(err)
+	}
+	if _, err := ParseFEN(strings.Replace(full, "[", "[P", 1)); err == nil {
+		t.Error("Pocket with 17 pawns was read")
+	}
+	if pos = NewPosition(strings.Replace(full, "[", "[P", 1)); pos.FEN() != full {
+		t.Errorf("Pocket with 17 pawns read as %s", pos.FEN())
+	}
+	//pawns on the board (promoted pieces too) could end up in the pocket, so they count towards the 16
+	full = "4k3/8/8/8/8/8/3p4/4K3[" + strings.Repeat("P", MAXPOCKET-1) + "] w - - 0 1"
+	for _, fen := range []string{strings.Replace(full, "[", "[P", 1), strings.Replace(full, "4k3", "3Q~k3", 1)} {
+		if _, err := ParseFEN(fen); err == nil {
+			t.Errorf("%s has more than 16 pawns, but was read", fen)
+		}
+	}
+	pos, err := ParseFEN(full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, _ := ParseMove(&pos, "e1d2")
+	pos.DoMove(m)
+	if !strings.Contains(pos.FEN(), "["+strings.Repeat("P", MAXPOCKET)+"]") {
+		t.Errorf("Capture didn't fill the pocket: %s", pos.FEN())
+	}
+
+	//pawns can't be dropped on the back ranks, and drops can block check
+	pos = NewPosition("4k3/8/8/8/8/8/8/r3K3[P] w - - 0 1")
+	if _, ok := ParseMove(&pos, "P@b1"); ok {
+		t.Error("Pawn dropped on the first rank")
+	}
+	pos = NewPosition("4k3/8/8/8/8/8/8/r3K3[N] w - - 0 1")
+	mirrored := pos.Mirror()
+	back := mirrored.Mirror()
+	if m, ok := ParseMove(&pos, "N@c1"); !ok || back.FEN() != pos.FEN() {
+		t.Errorf("Couldn't block check with a drop (%v), or mirroring lost the pocket", m)
+	}
+}
@@ -2,7 +2,7 @@
 
 echo "Cleaning up!"
 
-go fmt 
+go fmt ./...
 
 echo "Building Aristocrat 2"
 echo "----------"
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+	"github.com/BenNicholls/Aristocrat2/search"
+)
+
+//self-play games are adjudicated as a win once one side has been this far ahead for WINPLIES plies in a row
+const (
+	WINSCORE int = 2000
+	WINPLIES int = 4
+)
+
+//self-play games still going after this many plies are called a draw
+const MAXPLIES int = 400
+
+//settings for the self-play games
+type datagenOptions struct {
+	randomMoves int //plies of random moves to start each game with
+	limits      search.SearchLimits
+	hashSize    int
+}
+
+//a position from a self-play game, waiting for the game's result
+type sample struct {
+	fen   string
+	score int //from white's point of view
+}
+
+//runs the datagen command: aristocrat2 datagen [options]. plays games against itself and writes quiet
+//positions from them, one per line as "FEN | score | result", where the score is the search's in
+//centipawns and the result is the game's (1.0, 0.5 or 0.0), both from white's point of view. the tune
+//command reads these directly.
+func runDatagen(args []string) int {
+	flags := flag.NewFlagSet("datagen", flag.ExitOnError)
+	out := flags.String("out", "data.txt", "file to write the positions to")
+	count := flags.Int("positions", 100000, "number of positions to write")
+	seed := flags.Int64("seed", 0, "seed for the random openings (0 picks one)")
+	threads := flags.Int("threads", runtime.NumCPU(), "number of games to play at once")
+	nodes := flags.Int("nodes", 5000, "nodes to search each move")
+	depth := flags.Int("depth", 0, "depth to search each move, instead of -nodes")
+	randomMoves := flags.Int("random", 8, "random plies at the start of each game")
+	hashSize := flags.Int("hash", search.DEFAULTHASHSIZE, "hashtable size in MB for each game")
+	flags.Parse(args)
+
+	if *count < 1 || *threads < 1 || (*nodes < 1 && *depth < 1) {
+		fmt.Fprintln(os.Stderr, "Need at least one position, one thread and a search limit")
+		return 2
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	options := datagenOptions{randomMoves: *randomMoves, hashSize: *hashSize}
+	options.limits = search.SearchLimits{MultiPV: 1, Nodes: *nodes}
+	if *depth > 0 {
+		options.limits = search.SearchLimits{MultiPV: 1, Depth: *depth}
+	}
+
+	file, err := os.Create(*out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not create data file:", err)
+		return 1
+	}
+	defer file.Close()
+	output := bufio.NewWriter(file)
+	defer output.Flush()
+
+	fmt.Printf("Generating %d positions with seed %d, %d threads\n", *count, *seed, *threads)
+	stop := make(chan struct{})
+	options.limits.Stop = stop
+	games := make(chan []string)
+	var workers sync.WaitGroup
+	for i := 0; i < *threads; i++ {
+		workers.Add(1)
+		go func(seed int64) {
+			defer workers.Done()
+			datagenWorker(rand.New(rand.NewSource(seed)), options, stop, games)
+		}(*seed + int64(i))
+	}
+	go func() {
+		workers.Wait()
+		close(games)
+	}()
+
+	//the workers can finish a game or two after we've got enough, so keep reading until they stop
+	written, played := 0, 0
+	startTime, lastReport := time.Now(), time.Now()
+	for lines := range games {
+		played++
+		for _, line := range lines {
+			if written == *count {
+				break
+			}
+			if _, err := fmt.Fprintln(output, line); err != nil {
+				fmt.Fprintln(os.Stderr, "Could not write data:", err)
+				return 1
+			}
+			written++
+		}
+		if written == *count {
+			select {
+			case <-stop:
+			default:
+				close(stop)
+			}
+		}
+		if time.Since(lastReport) > 10*time.Second || written == *count {
+			lastReport = time.Now()
+			elapsed := time.Since(startTime).Seconds()
+			fmt.Printf("%d/%d positions from %d games, %.0f positions/second\n", written, *count, played, float64(written)/elapsed)
+		}
+	}
+	fmt.Println("Positions written to", *out)
+	return 0
+}
+
+//plays self-play games until stopped, sending the labelled positions of each finished game
+func datagenWorker(rng *rand.Rand, options datagenOptions, stop <-chan struct{}, games chan<- []string) {
+	engine := search.NewEngine()
+	engine.SetHashSize(options.hashSize)
+	for {
+		samples, result, ok := selfPlay(engine, rng, options, stop)
+		if !ok {
+			return
+		}
+		lines := make([]string, len(samples))
+		for i, s := range samples {
+			lines[i] = fmt.Sprintf("%s | %d | %.1f", s.fen, s.score, result)
+		}
+		games <- lines
+	}
+}
+
+//plays a game from a random opening. returns the quiet positions it went through and the result for
+//white, or not ok if it was stopped before the end.
+func selfPlay(engine *search.Engine, rng *rand.Rand, options datagenOptions, stop <-chan struct{}) (samples []sample, result float64, ok bool) {
+	pos := randomOpening(rng, options.randomMoves)
+	seen := map[uint64]int{pos.Hash: 1}
+	whiteWinning, blackWinning := 0, 0 //plies in a row
+	for ply := 0; ; ply++ {
+		moves, _ := board.Movegen(&pos)
+		if len(moves) == 0 {
+			if pos.InCheck() {
+				return samples, 0.5 - 0.5*float64(board.ScoreModifier[pos.ToMove]), true
+			}
+			return samples, 0.5, true
+		}
+		if pos.FiftyMoveCounter >= 100 || seen[pos.Hash] >= 3 || insufficientMaterial(&pos) || ply >= MAXPLIES {
+			return samples, 0.5, true
+		}
+
+		lines, _ := engine.Search(&pos, options.limits, nil)
+		select {
+		case <-stop:
+			return nil, 0, false
+		default:
+		}
+		if len(lines) == 0 || len(lines[0].Variation) == 0 {
+			return nil, 0, false
+		}
+		score, best := lines[0].Score, lines[0].Variation[0]
+
+		//positions where the eval can't be trusted without a search aren't worth learning from
+		if !pos.InCheck() && !best.Capture() && !best.Promote() && lines[0].Result == search.None && abs(score) < WINSCORE {
+			samples = append(samples, sample{pos.FEN(), score})
+		}
+
+		switch {
+		case score >= WINSCORE:
+			whiteWinning, blackWinning = whiteWinning+1, 0
+		case score <= -WINSCORE:
+			whiteWinning, blackWinning = 0, blackWinning+1
+		default:
+			whiteWinning, blackWinning = 0, 0
+		}
+		if whiteWinning >= WINPLIES {
+			return samples, 1, true
+		} else if blackWinning >= WINPLIES {
+			return samples, 0, true
+		}
+
+		pos.DoMove(best)
+		seen[pos.Hash]++
+	}
+}
+
+//plays random legal moves from the start position, starting again if the game ends on the way
+func randomOpening(rng *rand.Rand, plies int) board.Position {
+	for {
+		pos := board.NewPosition("startpos")
+		for i := 0; i < plies; i++ {
+			moves, _ := board.Movegen(&pos)
+			if len(moves) == 0 {
+				break
+			}
+			pos.DoMove(moves[rng.Intn(len(moves))])
+		}
+		if moves, _ := board.Movegen(&pos); len(moves) > 0 {
+			return pos
+		}
+	}
+}
+
+//only kings, or kings and a single minor piece
+func insufficientMaterial(p *board.Position) bool {
+	if p.Pieces[board.PAWN]|p.Pieces[board.ROOK]|p.Pieces[board.QUEEN] != 0 {
+		return false
+	}
+	return board.CountBits(p.Pieces[board.KNIGHT]|p.Pieces[board.BISHOP]) <= 1
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+	"github.com/BenNicholls/Aristocrat2/search"
+)
+
+func TestSelfPlay(t *testing.T) {
+	options := datagenOptions{randomMoves: 8, limits: search.SearchLimits{MultiPV: 1, Depth: 2}, hashSize: 1}
+	engine := search.NewEngine()
+	rng := rand.New(rand.NewSource(1))
+	for game := 0; game < 3; game++ {
+		samples, result, ok := selfPlay(engine, rng, options, nil)
+		if !ok || (result != 0 && result != 0.5 && result != 1) {
+			t.Fatalf("Game %d ended badly: %v %v", game, result, ok)
+		}
+		for _, s := range samples {
+			pos, err := board.ParseFEN(s.fen)
+			if err != nil || pos.InCheck() {
+				t.Errorf("Bad sample %s", s.fen)
+			}
+		}
+	}
+}
@@ -0,0 +1,208 @@
+//Package eval scores positions for the search.
+package eval
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+	"github.com/BenNicholls/Aristocrat2/nnue"
+)
+
+//Evaluator scores positions with its own weights and network, so engines with different ones can run
+//side by side. change them between searches, not during. make them with NewEvaluator.
+type Evaluator struct {
+	weights Weights
+	network *nnue.Network //nil for the classical evaluation
+}
+
+//makes an evaluator with the default weights
+func NewEvaluator() *Evaluator {
+	return &Evaluator{weights: append(Weights{}, DefaultWeights...)}
+}
+
+//the weights the evaluator is using
+func (ev *Evaluator) Weights() Weights {
+	return append(Weights{}, ev.weights...)
+}
+
+func (ev *Evaluator) SetWeights(w Weights) {
+	ev.weights = append(Weights{}, w...)
+}
+
+//the nnue network the evaluator uses, or nil if it's using the classical evaluation
+func (ev *Evaluator) Network() *nnue.Network {
+	return ev.network
+}
+
+//changes the network. nil goes back to the classical evaluation.
+func (ev *Evaluator) SetNetwork(n *nnue.Network) {
+	ev.network = n
+}
+
+//returns the evaluation of the position in centipawns, from the point of view of the side to move. uses
+//the nnue network if one is loaded, and the classical evaluation if not. the nnue accumulator is built
+//from scratch every time, so searches evaluate with a Stack instead.
+func (ev *Evaluator) Eval(p *board.Position) (score int) {
+	if ev.useNNUE(p) {
+		var a nnue.Accumulator
+		accumulate(&a, ev.network, p)
+		return nnue.Evaluate(&a, p.ToMove)
+	}
+	return ev.Classical(p)
+}
+
+//networks are trained on standard chess, so variants always get the classical evaluation
+func (ev *Evaluator) useNNUE(p *board.Position) bool {
+	return ev.network != nil && p.Variant == board.STANDARD
+}
+
+//the hand written evaluation, from the point of view of the side to move
+func (ev *Evaluator) Classical(p *board.Position) (score int) {
+	e := evaluator{weights: ev.weights}
+	return board.ScoreModifier[p.ToMove] * e.evaluate(p)
+}
+
+//Coefficients gives how many times each weight counts in the evaluation of the position, from white's
+//point of view: the eval is the sum of weights[i] * coefficients[i]. this is what the tuner works from.
+func Coefficients(p *board.Position) []int {
+	e := evaluator{weights: DefaultWeights, coefficients: make([]int, NUMWEIGHTS)}
+	e.evaluate(p)
+	return e.coefficients
+}
+
+//the parts of the evaluation, for traces
+const (
+	T_MATERIAL int = iota
+	T_VARIANT
+
+	NUMTERMS
+)
+
+var TermNames = [NUMTERMS]string{"Material", "Variant"}
+
+//the game phase runs from PHASEMAX with all the pieces on the board down to 0 with none. pawns and kings
+//don't count.
+const PHASEMAX int = 24
+
+var phaseValues = [6]int{0, 1, 1, 2, 4, 0}
+
+//Score is a value in the middlegame and in the endgame, which get blended by the game phase
+type Score struct {
+	MG, EG int
+}
+
+//evaluator adds up the terms of the evaluation, from white's point of view
+type evaluator struct {
+	weights      Weights
+	term         int //the part of the evaluation being added to
+	score        Score
+	coefficients []int  //if set, how many times each weight has been used
+	trace        *Trace //if set, where the terms are recorded
+}
+
+//adds a weight that applies count times for a colour. weights count the same in the middlegame and the
+//endgame, so blending by phase doesn't change the sum the tuner sees.
+func (e *evaluator) add(colour, weight, count int) {
+	value := e.weights[weight] * count
+	e.score.MG += value * board.ScoreModifier[colour]
+	e.score.EG += value * board.ScoreModifier[colour]
+	if e.coefficients != nil {
+		e.coefficients[weight] += count * board.ScoreModifier[colour]
+	}
+	if e.trace != nil {
+		e.trace.Terms[e.term][colour].MG += value
+		e.trace.Terms[e.term][colour].EG += value
+	}
+}
+
+//returns the score from white's point of view
+func (e *evaluator) evaluate(p *board.Position) int {
+	e.term = T_MATERIAL
+	for colour := board.WHITE; colour <= board.BLACK; colour++ {
+		if p.Variant == board.ANTICHESS {
+			e.add(colour, W_ANTIPIECE, board.CountBits(p.Colours[colour]))
+			continue
+		}
+		//crazyhouse pieces in the pocket count as much as the ones on the board
+		for piece := board.PAWN; piece <= board.QUEEN; piece++ {
+			e.add(colour, W_PAWN+piece, board.CountBits(p.Colours[colour]&p.Pieces[piece])+p.Pockets[colour][piece])
+		}
+	}
+
+	e.term = T_VARIANT
+	for colour := board.WHITE; colour <= board.BLACK; colour++ {
+		switch p.Variant {
+		case board.THREECHECK:
+			if p.Checks[colour] == 1 {
+				e.add(colour, W_ONECHECK, 1)
+			} else if p.Checks[colour] == 2 {
+				e.add(colour, W_TWOCHECKS, 1)
+			}
+		case board.KINGOFTHEHILL:
+			e.add(colour, W_KINGTOHILL, 3-board.HillDistance(p.GetKingSquare(colour)))
+		}
+	}
+
+	phase := gamePhase(p)
+	score := (e.score.MG*phase + e.score.EG*(PHASEMAX-phase)) / PHASEMAX
+	if e.trace != nil {
+		e.trace.Phase, e.trace.Classical = phase, score
+	}
+	return score
+}
+
+func gamePhase(p *board.Position) (phase int) {
+	for piece := board.KNIGHT; piece <= board.QUEEN; piece++ {
+		phase += phaseValues[piece] * board.CountBits(p.Pieces[piece])
+	}
+	return min(phase, PHASEMAX)
+}
+
+//Trace is the classical evaluation of a position broken down into its terms
+type Trace struct {
+	Terms     [NUMTERMS][2]Score //by colour, each from its own point of view
+	Phase     int
+	Classical int //blended by phase, from white's point of view
+	NNUE      int //from white's point of view, if a network is loaded
+	UsedNNUE  bool
+	Final     int //what Eval gives, from white's point of view
+}
+
+//TraceEval evaluates the position the way Eval does, keeping track of the terms
+func (ev *Evaluator) TraceEval(p *board.Position) (t Trace) {
+	e := evaluator{weights: ev.weights, trace: &t}
+	e.evaluate(p)
+	t.Final = t.Classical
+	if ev.useNNUE(p) {
+		t.NNUE = ev.Eval(p) * board.ScoreModifier[p.ToMove]
+		t.Final, t.UsedNNUE = t.NNUE, true
+	}
+	return
+}
+
+//the trace as a table, in pawns
+func (t Trace) String() string {
+	var s strings.Builder
+	s.WriteString("     Term    |    White    |    Black    |    Total\n")
+	s.WriteString("             |   MG    EG  |   MG    EG  |   MG    EG\n")
+	s.WriteString(" ------------+-------------+-------------+------------\n")
+	for term, scores := range t.Terms {
+		white, black := scores[board.WHITE], scores[board.BLACK]
+		fmt.Fprintf(&s, " %11s | %s %s | %s %s | %s %s\n", TermNames[term],
+			pawns(white.MG), pawns(white.EG), pawns(black.MG), pawns(black.EG),
+			pawns(white.MG-black.MG), pawns(white.EG-black.EG))
+	}
+	s.WriteString(" ------------+-------------+-------------+------------\n\n")
+	fmt.Fprintf(&s, "Phase: %d/%d\n", t.Phase, PHASEMAX)
+	fmt.Fprintf(&s, "Classical evaluation: %+.2f (white side)\n", float64(t.Classical)/100)
+	if t.UsedNNUE {
+		fmt.Fprintf(&s, "NNUE evaluation:      %+.2f (white side)\n", float64(t.NNUE)/100)
+	}
+	fmt.Fprintf(&s, "Final evaluation:     %+.2f (white side)\n", float64(t.Final)/100)
+	return s.String()
+}
+
+func pawns(centipawns int) string {
+	return fmt.Sprintf("%5.2f", float64(centipawns)/100)
+}
@@ -0,0 +1,90 @@
+package eval
+
+import (
+	"bufio"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+	"github.com/BenNicholls/Aristocrat2/nnue"
+)
+
+//the trace, the eval and the tuner's coefficients should all agree, whatever the weights
+func TestTrace(t *testing.T) {
+	ev := NewEvaluator()
+	weights := ev.Weights()
+	weights[W_KNIGHT] = 320
+	ev.SetWeights(weights)
+	for i, fen := range []string{
+		"startpos",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R b KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	} {
+		pos := board.NewPosition(fen)
+		trace := ev.TraceEval(&pos)
+		score := ev.Eval(&pos) * board.ScoreModifier[pos.ToMove]
+		if trace.Final != score {
+			t.Errorf("%s: trace gives %d, eval gives %d", fen, trace.Final, score)
+		}
+		if phase := []int{PHASEMAX, PHASEMAX, 4}[i]; trace.Phase != phase {
+			t.Errorf("%s: phase is %d, expected %d", fen, trace.Phase, phase)
+		}
+
+		sum := 0
+		for i, count := range Coefficients(&pos) {
+			sum += count * weights[i]
+		}
+		if sum != score {
+			t.Errorf("%s: coefficients give %d, eval gives %d", fen, sum, score)
+		}
+	}
+
+	//evaluators don't share their weights
+	pos := board.NewPosition("4k3/8/8/8/8/8/8/1N2K3 w - - 0 1")
+	if a, b := ev.Eval(&pos), NewEvaluator().Eval(&pos); a != 320 || b != 300 {
+		t.Errorf("Knight up scores %d and %d, expected 320 and 300", a, b)
+	}
+}
+
+//the eval should be the same for both colours: mirrored positions get the same score for the side to move
+func TestSymmetry(t *testing.T) {
+	file, err := os.Open("../test/perftSuite.epd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var positions []board.Position
+	lines := bufio.NewScanner(file)
+	for lines.Scan() {
+		if fen, _, _ := strings.Cut(lines.Text(), ";"); strings.TrimSpace(fen) != "" {
+			positions = append(positions, board.NewPosition(fen))
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for game := 0; game < 50; game++ {
+		pos := board.NewPosition("startpos")
+		for ply := 0; ply < 100; ply++ {
+			moves, _ := board.Movegen(&pos)
+			if len(moves) == 0 {
+				break
+			}
+			pos.DoMove(moves[rng.Intn(len(moves))])
+			positions = append(positions, pos.Copy())
+		}
+	}
+
+	//the network's features are seen from each side's point of view, so a random one should be symmetric too
+	for _, network := range []*nnue.Network{nil, nnue.RandomNetwork(1)} {
+		ev := NewEvaluator()
+		ev.SetNetwork(network)
+		for _, pos := range positions {
+			mirror := pos.Mirror()
+			if a, b := ev.Eval(&pos), ev.Eval(&mirror); a != b {
+				t.Errorf("%s scores %d, mirrored %d (nnue: %v)", pos.FEN(), a, b, network != nil)
+			}
+		}
+	}
+}
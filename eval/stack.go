@@ -0,0 +1,92 @@
+package eval
+
+import (
+	"github.com/BenNicholls/Aristocrat2/board"
+	"github.com/BenNicholls/Aristocrat2/nnue"
+)
+
+//Stack evaluates the positions along the line a search is on. it keeps an nnue accumulator for each
+//ply, worked out from the one before it, so the first layer is only computed in full when a king moves.
+//each search thread needs its own. with the classical evaluation it doesn't keep anything.
+type Stack struct {
+	ev           *Evaluator
+	active       bool //whether the accumulators are being kept
+	ply          int
+	accumulators []nnue.Accumulator
+}
+
+//starts a stack for searching from the root position
+func (ev *Evaluator) NewStack(root *board.Position) *Stack {
+	s := &Stack{ev: ev, active: ev.useNNUE(root)}
+	if s.active {
+		s.accumulators = make([]nnue.Accumulator, 1, 64)
+		accumulate(&s.accumulators[0], ev.network, root)
+	}
+	return s
+}
+
+//moves the stack on to child, the position after a move from parent. parent is the position the stack
+//is on.
+func (s *Stack) Push(parent, child *board.Position) {
+	s.ply++
+	if !s.active {
+		return
+	}
+	if s.ply == len(s.accumulators) {
+		s.accumulators = append(s.accumulators, nnue.Accumulator{})
+	}
+	a := &s.accumulators[s.ply]
+	*a = s.accumulators[s.ply-1]
+	update(a, s.ev.network, parent, child)
+}
+
+//goes back to the position before the last Push
+func (s *Stack) Pop() {
+	s.ply--
+}
+
+//evaluates the position the stack is on, the way Evaluator.Eval does
+func (s *Stack) Eval(p *board.Position) int {
+	if s.active {
+		return nnue.Evaluate(&s.accumulators[s.ply], p.ToMove)
+	}
+	return s.ev.Classical(p)
+}
+
+//builds both sides of the accumulator from scratch
+func accumulate(a *nnue.Accumulator, n *nnue.Network, p *board.Position) {
+	refresh(a, n, p, board.WHITE)
+	refresh(a, n, p, board.BLACK)
+}
+
+func refresh(a *nnue.Accumulator, n *nnue.Network, p *board.Position, perspective int) {
+	a.Reset(n, perspective, p.GetKingSquare(perspective))
+	for colour := board.WHITE; colour <= board.BLACK; colour++ {
+		for piece := board.PAWN; piece <= board.QUEEN; piece++ {
+			board.ForEachBit(p.Colours[colour]&p.Pieces[piece], func(square int) {
+				a.AddFeature(perspective, colour, piece, square)
+			})
+		}
+	}
+}
+
+//turns parent's accumulator into child's, from the pieces the move put on and took off the board. a
+//side whose king moved is built from scratch, since all of its features depend on where the king is.
+func update(a *nnue.Accumulator, n *nnue.Network, parent, child *board.Position) {
+	for perspective := board.WHITE; perspective <= board.BLACK; perspective++ {
+		if child.GetKingSquare(perspective) != parent.GetKingSquare(perspective) {
+			refresh(a, n, child, perspective)
+			continue
+		}
+		for _, c := range child.PieceChanges() {
+			if c.Piece() == board.KING { //kings aren't features, they pick which ones are used
+				continue
+			}
+			if c.Added() {
+				a.AddFeature(perspective, c.Colour(), c.Piece(), c.Square())
+			} else {
+				a.RemoveFeature(perspective, c.Colour(), c.Piece(), c.Square())
+			}
+		}
+	}
+}
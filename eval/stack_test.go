@@ -0,0 +1,41 @@
+package eval
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+	"github.com/BenNicholls/Aristocrat2/nnue"
+)
+
+//plays random games down the stack with a network loaded, checking the incrementally updated
+//accumulators against ones built from scratch after every move
+func TestStack(t *testing.T) {
+	ev := NewEvaluator()
+	ev.SetNetwork(nnue.RandomNetwork(1))
+	rng := rand.New(rand.NewSource(1))
+	for game := 0; game < 20; game++ {
+		pos := board.NewPosition("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+		evals := ev.NewStack(&pos)
+		for ply := 0; ply < 100; ply++ {
+			moves, _ := board.Movegen(&pos)
+			if len(moves) == 0 {
+				break
+			}
+			m := moves[rng.Intn(len(moves))]
+			next := pos
+			next.DoMove(m)
+			evals.Push(&pos, &next)
+			pos = next
+
+			var fresh nnue.Accumulator
+			accumulate(&fresh, ev.network, &pos)
+			if fresh != evals.accumulators[evals.ply] {
+				t.Fatalf("Accumulator wrong after %s in game %d", m.UCIstring(), game)
+			}
+			if a, b := evals.Eval(&pos), ev.Eval(&pos); a != b {
+				t.Fatalf("Stack evaluates %s as %d, Eval as %d", pos.FEN(), a, b)
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package eval
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//Weights are all the numbers the evaluation is made of, in centipawns. they live in one vector so the
+//tuner can work on them together.
+type Weights []int
+
+//indexes of the weights
+const (
+	W_PAWN int = iota
+	W_KNIGHT
+	W_BISHOP
+	W_ROOK
+	W_QUEEN
+	W_ONECHECK   //three-check: having given one check
+	W_TWOCHECKS  //three-check: having given two
+	W_KINGTOHILL //king of the hill: each king move closer to the centre than the edge of the board
+	W_ANTIPIECE  //antichess: each piece left on the board, instead of the usual material
+
+	NUMWEIGHTS
+)
+
+//names of the weights, for weights files
+var WeightNames = [NUMWEIGHTS]string{"PawnValue", "KnightValue", "BishopValue", "RookValue", "QueenValue", "OneCheckGiven", "TwoChecksGiven", "KingToHill", "AntichessPiece"}
+
+//the hand picked weights the engine starts with
+var DefaultWeights = Weights{100, 300, 300, 500, 900, 80, 300, 40, -100}
+
+//reads a weights file. weights the file doesn't mention keep their defaults.
+func LoadWeights(filename string) (Weights, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	w, err := ReadWeights(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return w, nil
+}
+
+//reads weights in the form WriteTo writes them: one "name value" per line. blank lines and lines
+//starting with # are skipped.
+func ReadWeights(r io.Reader) (Weights, error) {
+	w := append(Weights{}, DefaultWeights...)
+	lines := bufio.NewScanner(r)
+	for lineNumber := 1; lines.Scan(); lineNumber++ {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a name and a value", lineNumber)
+		}
+		i := weightIndex(fields[0])
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: unknown weight %s", lineNumber, fields[0])
+		}
+		value, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: bad value for %s", lineNumber, fields[0])
+		}
+		w[i] = value
+	}
+	return w, lines.Err()
+}
+
+func weightIndex(name string) int {
+	for i, n := range WeightNames {
+		if strings.EqualFold(n, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+//writes the weights so ReadWeights (and the engine's WeightsFile option) can load them
+func (w Weights) WriteTo(out io.Writer) (n int64, err error) {
+	for i, value := range w {
+		written, err := fmt.Fprintf(out, "%s %d\n", WeightNames[i], value)
+		n += int64(written)
+		if err != nil {
+			return n, err
+		}
+	}
+	return
+}
@@ -0,0 +1,3 @@
+module github.com/BenNicholls/Aristocrat2
+
+go 1.22
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/BenNicholls/Aristocrat2/match"
+	"github.com/BenNicholls/Aristocrat2/search"
+)
+
+//engine name for playing with the engine in this program instead of a UCI binary
+const INTERNALENGINE string = "internal"
+
+//UCI options for an engine, from repeated -option flags like -option1 Hash=16
+type engineOptions map[string]string
+
+func (o engineOptions) String() string {
+	return fmt.Sprint(map[string]string(o))
+}
+
+func (o engineOptions) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return errors.New("options are given as name=value")
+	}
+	o[name] = value
+	return nil
+}
+
+//flags describing the engines and games, shared by the match and sprt commands
+type matchFlags struct {
+	engines     [2]*string
+	options     [2]engineOptions
+	openings    *string
+	tc          *string
+	moveTime    *float64
+	depth       *int
+	resignMoves *int
+	resignScore *int
+}
+
+func addMatchFlags(flags *flag.FlagSet) *matchFlags {
+	f := &matchFlags{options: [2]engineOptions{{}, {}}}
+	f.engines[0] = flags.String("engine1", INTERNALENGINE, "first engine: path to a UCI binary, or \""+INTERNALENGINE+"\" for this one")
+	f.engines[1] = flags.String("engine2", "", "second engine: path to a UCI binary, or \""+INTERNALENGINE+"\" for this one")
+	flags.Var(f.options[0], "option1", "UCI option for the first engine, as name=value. can be repeated")
+	flags.Var(f.options[1], "option2", "UCI option for the second engine, as name=value. can be repeated")
+	f.openings = flags.String("openings", "", "EPD or PGN file of openings. each is played twice, once with each colour")
+	f.tc = flags.String("tc", "10+0.1", "time control: [moves/]seconds[+increment]")
+	f.moveTime = flags.Float64("st", 0, "fixed time per move in seconds, instead of -tc")
+	f.depth = flags.Int("depth", 0, "fixed search depth per move with no time limit, instead of -tc")
+	f.resignMoves = flags.Int("resignmoves", 0, "resign after this many moves in a row at or below -resignscore (0 never resigns)")
+	f.resignScore = flags.Int("resignscore", 600, "score in centipawns for -resignmoves")
+	return f
+}
+
+//sets up a match as the flags describe, with the engines started. the players need closing when the
+//match is done, even if there's an error.
+func (f *matchFlags) newMatch() (m *match.Match, err error) {
+	m = &match.Match{Adjudication: match.Adjudication{ResignMoves: *f.resignMoves, ResignScore: *f.resignScore}}
+	if *f.engines[1] == "" {
+		return m, errors.New("-engine2 is needed")
+	}
+	switch {
+	case *f.depth > 0:
+		m.TimeControl = match.TimeControl{Depth: *f.depth}
+	case *f.moveTime > 0:
+		m.TimeControl = match.TimeControl{MoveTime: int(*f.moveTime * 1000)}
+	default:
+		if m.TimeControl, err = match.ParseTimeControl(*f.tc); err != nil {
+			return m, err
+		}
+	}
+	if *f.openings != "" {
+		if m.Openings, err = match.LoadOpenings(*f.openings); err != nil {
+			return m, fmt.Errorf("could not load openings: %w", err)
+		}
+	}
+	for i := range m.Players {
+		if m.Players[i], err = newPlayer(*f.engines[i], f.options[i]); err != nil {
+			m.Players[i] = nil
+			return m, fmt.Errorf("could not start engine: %w", err)
+		}
+	}
+
+	//engines with the same name (two builds of this one, say) need telling apart in the PGN
+	if m.Players[0].Name() == m.Players[1].Name() {
+		for i := range m.Players {
+			m.Players[i] = renamedPlayer{m.Players[i], fmt.Sprintf("%s (%d)", m.Players[i].Name(), i+1)}
+		}
+	}
+	return m, nil
+}
+
+func closePlayers(m *match.Match) {
+	for _, p := range m.Players {
+		if p != nil {
+			p.Close()
+		}
+	}
+}
+
+//runs the match command: aristocrat2 match -engine2 <path> [options]. plays the games, writes them to
+//a PGN file and prints the score as it goes.
+func runMatch(args []string) int {
+	flags := flag.NewFlagSet("match", flag.ExitOnError)
+	matchOptions := addMatchFlags(flags)
+	games := flags.Int("games", 10, "number of games to play")
+	pgnFile := flags.String("pgn", "match.pgn", "file to write the games to")
+	flags.Parse(args)
+
+	m, err := matchOptions.newMatch()
+	defer closePlayers(m)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	m.Games = *games
+
+	pgn, err := os.Create(*pgnFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not create PGN file:", err)
+		return 1
+	}
+	defer pgn.Close()
+	m.PGN = pgn
+
+	names := [2]string{m.Players[0].Name(), m.Players[1].Name()}
+	fmt.Printf("Match: %s vs %s, %d games at %s\n", names[0], names[1], m.Games, m.TimeControl)
+	m.GameOver = func(g match.Game, s match.Score) bool {
+		fmt.Printf("Game %d: %s vs %s: %s {%s}\n", g.Round, g.White, g.Black, g.Result, g.Reason)
+		fmt.Printf("Score of %s vs %s: %s\n", names[0], names[1], s)
+		return false
+	}
+	if _, err := m.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not write PGN:", err)
+		return 1
+	}
+	return 0
+}
+
+type renamedPlayer struct {
+	match.Player
+	name string
+}
+
+func (r renamedPlayer) Name() string {
+	return r.name
+}
+
+//starts an engine for a match. the internal engine understands the Hash and Threads options.
+func newPlayer(engine string, options engineOptions) (match.Player, error) {
+	if engine != INTERNALENGINE {
+		return match.NewUCIPlayer(engine, options)
+	}
+
+	e := search.NewEngine()
+	for name, value := range options {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("option %s needs a number", name)
+		}
+		switch strings.ToLower(name) {
+		case "hash":
+			e.SetHashSize(n)
+		case "threads":
+			e.Threads = max(n, 1)
+		default:
+			return nil, errors.New("the internal engine has no option " + name)
+		}
+	}
+	return match.NewEnginePlayer(e), nil
+}
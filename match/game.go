@@ -0,0 +1,200 @@
+package match
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+)
+
+//how far past its time a player can go before losing on time. covers the overhead of talking to it.
+const TIMEMARGIN = 100 * time.Millisecond
+
+//TimeControl is the time each player gets. either a clock (with moves per session and increment), a
+//fixed time per move or a fixed depth with no time limit.
+type TimeControl struct {
+	Moves     int //per session, 0 for the whole game
+	Base      int //msec
+	Increment int //msec
+	MoveTime  int //msec per move. if set, the clock isn't used
+	Depth     int //plies per move. if set, there's no time limit at all
+}
+
+//reads a time control in the usual form: [moves/]seconds[+increment], ie. 40/60 or 10+0.1
+func ParseTimeControl(s string) (tc TimeControl, err error) {
+	if moves, rest, ok := strings.Cut(s, "/"); ok {
+		if tc.Moves, err = strconv.Atoi(moves); err != nil || tc.Moves < 1 {
+			return tc, errors.New("bad moves per session in time control: " + s)
+		}
+		s = rest
+	}
+	base, inc, _ := strings.Cut(s, "+")
+	seconds, err := strconv.ParseFloat(base, 64)
+	if err != nil || seconds <= 0 {
+		return tc, errors.New("bad time in time control: " + s)
+	}
+	tc.Base = int(seconds * 1000)
+	if inc != "" {
+		seconds, err := strconv.ParseFloat(inc, 64)
+		if err != nil || seconds < 0 {
+			return tc, errors.New("bad increment in time control: " + s)
+		}
+		tc.Increment = int(seconds * 1000)
+	}
+	return tc, nil
+}
+
+//in the same form ParseTimeControl reads, as used in the PGN TimeControl tag
+func (tc TimeControl) String() string {
+	if tc.Depth > 0 {
+		return "-" //PGN for no time control
+	} else if tc.MoveTime > 0 {
+		return fmt.Sprintf("%g/move", float64(tc.MoveTime)/1000)
+	}
+	s := fmt.Sprintf("%g", float64(tc.Base)/1000)
+	if tc.Moves > 0 {
+		s = fmt.Sprintf("%d/%s", tc.Moves, s)
+	}
+	if tc.Increment > 0 {
+		s += fmt.Sprintf("+%g", float64(tc.Increment)/1000)
+	}
+	return s
+}
+
+//Adjudication sets when games are ended early. checkmate, stalemate, threefold repetition and the fifty
+//move rule always end the game.
+type Adjudication struct {
+	ResignMoves int //a player resigns after its score is at or below -ResignScore for this many moves in a row. 0 to never resign
+	ResignScore int //centipawns
+}
+
+//Game is a finished game
+type Game struct {
+	White, Black string
+	Round        int
+	StartFEN     string   //"startpos" or a FEN
+	Moves        []string //SAN, starting with the opening
+	OpeningMoves int      //how many of the moves came from the opening
+	TimeControl  TimeControl
+	Result       string //1-0, 0-1 or 1/2-1/2
+	Termination  string //PGN termination: normal, adjudication, time forfeit or rules infraction
+	Reason       string //what happened, ie. "Black is mated"
+	Date         time.Time
+}
+
+var colourNames = [2]string{"White", "Black"}
+
+//plays a game between the players from the opening
+func playGame(players [2]Player, opening Opening, tc TimeControl, adj Adjudication) (g Game) {
+	g = Game{White: players[board.WHITE].Name(), Black: players[board.BLACK].Name(), StartFEN: opening.FEN, TimeControl: tc, Date: time.Now()}
+	for _, p := range players {
+		if err := p.NewGame(); err != nil {
+			g.Result, g.Termination, g.Reason = "*", "abandoned", p.Name()+": "+err.Error()
+			return
+		}
+	}
+
+	pos := board.NewPosition(opening.FEN)
+	seen := map[uint64]int{pos.Hash: 1}
+	var played board.MoveList
+	play := func(m board.Move) {
+		g.Moves = append(g.Moves, pos.SAN(m))
+		played = append(played, m)
+		pos.DoMove(m)
+		seen[pos.Hash]++
+	}
+	for _, m := range opening.Moves {
+		play(m)
+	}
+	g.OpeningMoves = len(played)
+
+	//the side to move loses. the result is always from white's point of view
+	lose := func(termination, reason string) {
+		g.Result = "1-0"
+		if pos.ToMove == board.WHITE {
+			g.Result = "0-1"
+		}
+		g.Termination, g.Reason = termination, colourNames[pos.ToMove]+" "+reason
+	}
+	win := func(termination, reason string) {
+		g.Result = "0-1"
+		if pos.ToMove == board.WHITE {
+			g.Result = "1-0"
+		}
+		g.Termination, g.Reason = termination, colourNames[pos.ToMove]+" "+reason
+	}
+	draw := func(reason string) {
+		g.Result, g.Termination, g.Reason = "1/2-1/2", "normal", reason
+	}
+
+	clocks := [2]int{tc.Base, tc.Base}
+	movesMade := [2]int{}
+	resignCount := [2]int{}
+	for {
+		if moves, _ := board.Movegen(&pos); len(moves) == 0 {
+			switch pos.Outcome() {
+			case board.Lost:
+				if pos.InCheck() {
+					lose("normal", "is mated")
+				} else {
+					lose("normal", "loses by the "+pos.Variant.String()+" rules")
+				}
+			case board.Won:
+				win("normal", "wins by the "+pos.Variant.String()+" rules")
+			default:
+				draw("Stalemate")
+			}
+			return
+		}
+		if pos.FiftyMoveCounter >= 100 {
+			draw("Fifty move rule")
+			return
+		}
+		if seen[pos.Hash] >= 3 {
+			draw("Threefold repetition")
+			return
+		}
+
+		side := pos.ToMove
+		r := Request{StartFEN: opening.FEN, Moves: played, Position: &pos, Time: clocks, Increment: tc.Increment, MoveTime: tc.MoveTime, Depth: tc.Depth}
+		if tc.Moves > 0 {
+			r.MovesToGo = tc.Moves - movesMade[side]%tc.Moves
+		}
+		startTime := time.Now()
+		ms, score, err := players[side].Move(r)
+		elapsed := time.Since(startTime)
+		if err == errTimeout || (err == nil && r.Depth == 0 && elapsed > r.timeLeft()) {
+			lose("time forfeit", "loses on time")
+			return
+		} else if err != nil {
+			lose("rules infraction", "disconnects: "+err.Error())
+			return
+		}
+		m, ok := board.ParseMove(&pos, ms)
+		if !ok {
+			lose("rules infraction", "makes an illegal move: "+ms)
+			return
+		}
+
+		if tc.MoveTime == 0 && tc.Depth == 0 {
+			movesMade[side]++
+			clocks[side] = max(clocks[side]-int(elapsed.Milliseconds()), 0) + tc.Increment
+			if tc.Moves > 0 && movesMade[side]%tc.Moves == 0 {
+				clocks[side] += tc.Base
+			}
+		}
+		if adj.ResignMoves > 0 && score <= -adj.ResignScore {
+			resignCount[side]++
+			if resignCount[side] >= adj.ResignMoves {
+				lose("adjudication", "resigns")
+				return
+			}
+		} else {
+			resignCount[side] = 0
+		}
+		play(m)
+	}
+}
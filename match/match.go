@@ -0,0 +1,109 @@
+//Package match plays engines against each other: games between UCI engines (or the engine in this
+//program), PGN in and out, and the statistics to make sense of the results.
+package match
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+//Match plays a series of games between two players. each opening is played twice, once with each player
+//as white, and the players swap colours every game.
+type Match struct {
+	Players      [2]Player
+	Games        int
+	Openings     []Opening //games start from the standard position if there aren't any
+	TimeControl  TimeControl
+	Adjudication Adjudication
+	Round        int                               //games already played, when carrying on with a match. the next game is Round+1
+	PGN          io.Writer                         //each game is written here once it's over. can be nil
+	GameOver     func(g Game, s Score) (stop bool) //called after each game with the score so far. can be nil
+}
+
+//Score is a tally of results from the first player's point of view
+type Score struct {
+	Wins, Draws, Losses int
+}
+
+//plays the games and returns the score for them. stops early if writing the PGN fails or GameOver says so.
+func (m *Match) Run() (s Score, err error) {
+	openings := m.Openings
+	if len(openings) == 0 {
+		openings = []Opening{{FEN: "startpos"}}
+	}
+	for i := m.Round; i < m.Games; i++ {
+		players := m.Players
+		if i%2 == 1 {
+			players[0], players[1] = players[1], players[0]
+		}
+		g := playGame(players, openings[(i/2)%len(openings)], m.TimeControl, m.Adjudication)
+		g.Round = i + 1
+		s.Add(g, i%2 == 0)
+
+		if m.PGN != nil {
+			if err := WritePGN(m.PGN, g); err != nil {
+				return s, err
+			}
+		}
+		if m.GameOver != nil && m.GameOver(g, s) {
+			break
+		}
+	}
+	return s, nil
+}
+
+//counts the game's result. first is whether the first player had white. unfinished games don't count.
+func (s *Score) Add(g Game, first bool) {
+	switch {
+	case g.Result == "1/2-1/2":
+		s.Draws++
+	case (g.Result == "1-0") == first && g.Result != "*":
+		s.Wins++
+	case g.Result != "*":
+		s.Losses++
+	}
+}
+
+func (s Score) Games() int {
+	return s.Wins + s.Draws + s.Losses
+}
+
+//fraction of the points won, from 0 to 1
+func (s Score) Points() float64 {
+	if s.Games() == 0 {
+		return 0.5
+	}
+	return (float64(s.Wins) + float64(s.Draws)/2) / float64(s.Games())
+}
+
+//Elo estimates the rating difference between the players, and the margin of error with 95% confidence.
+//the difference is infinite if one side won every game.
+func (s Score) Elo() (diff, margin float64) {
+	n := float64(s.Games())
+	if n == 0 {
+		return 0, math.Inf(1)
+	}
+	mean := s.Points()
+	if mean == 0 || mean == 1 {
+		return eloDifference(mean), math.Inf(1)
+	}
+	variance := (float64(s.Wins)*math.Pow(1-mean, 2) + float64(s.Draws)*math.Pow(0.5-mean, 2) + float64(s.Losses)*math.Pow(mean, 2)) / n
+	deviation := 1.96 * math.Sqrt(variance/n)
+	return eloDifference(mean), (eloDifference(mean+deviation) - eloDifference(mean-deviation)) / 2
+}
+
+//the rating difference that gives the expected score
+func eloDifference(score float64) float64 {
+	if score >= 1 {
+		return math.Inf(1)
+	} else if score <= 0 {
+		return math.Inf(-1)
+	}
+	return -400 * math.Log10(1/score-1)
+}
+
+func (s Score) String() string {
+	diff, margin := s.Elo()
+	return fmt.Sprintf("W/D/L: %d/%d/%d [%.3f] %d games. Elo difference: %.1f +/- %.1f", s.Wins, s.Draws, s.Losses, s.Points(), s.Games(), diff, margin)
+}
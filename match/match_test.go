@@ -0,0 +1,84 @@
+package match
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/BenNicholls/Aristocrat2/search"
+)
+
+func TestPGNOpenings(t *testing.T) {
+	pgn := `[Event "test"]
+[White "a"]
+
+1. e4 {best by test} e5 (1... c5 2. Nf3) 2. Nf3 $1 Nc6 1-0
+
+[FEN "4k3/8/8/8/8/8/8/4K2R w K - 0 1"]
+
+1. O-O Kd7 ; castled
+*
+`
+	openings, err := readPGNOpenings(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(openings) != 2 {
+		t.Fatalf("Expected 2 openings, got %d", len(openings))
+	}
+	if openings[0].FEN != "startpos" || openings[0].Moves.UCIvariation() != "e2e4 e7e5 g1f3 b8c6" {
+		t.Errorf("First opening wrong: %s %s", openings[0].FEN, openings[0].Moves.UCIvariation())
+	}
+	if openings[1].Moves.UCIvariation() != "e1g1 e8d7" {
+		t.Errorf("Second opening wrong: %s", openings[1].Moves.UCIvariation())
+	}
+}
+
+//a mate in one should be found and adjudicated, and the game written out so it reads back in
+func TestPlayGame(t *testing.T) {
+	players := [2]Player{NewEnginePlayer(search.NewEngine()), NewEnginePlayer(search.NewEngine())}
+	opening := Opening{FEN: "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1"}
+	g := playGame(players, opening, TimeControl{MoveTime: 100}, Adjudication{})
+	if g.Result != "1-0" || g.Termination != "normal" || len(g.Moves) != 1 || g.Moves[0] != "Ra8#" {
+		t.Fatalf("Expected Ra8# 1-0, got %v %s {%s}", g.Moves, g.Result, g.Reason)
+	}
+
+	var out bytes.Buffer
+	WritePGN(&out, g)
+	openings, err := readPGNOpenings(&out)
+	if err != nil || len(openings) != 1 || openings[0].FEN != opening.FEN || len(openings[0].Moves) != 1 {
+		t.Errorf("Couldn't read back the game: %v\n%s", err, out.String())
+	}
+}
+
+func TestElo(t *testing.T) {
+	diff, margin := Score{Wins: 30, Draws: 40, Losses: 30}.Elo()
+	if diff != 0 || margin < 40 || margin > 60 {
+		t.Errorf("Expected 0 +/- ~50 for an even score, got %.1f +/- %.1f", diff, margin)
+	}
+	if diff, _ := (Score{Wins: 3, Draws: 0, Losses: 1}).Elo(); math.Abs(diff-190.8) > 0.1 {
+		t.Errorf("Expected 190.8 for a 75%% score, got %.1f", diff)
+	}
+}
+
+func TestSPRT(t *testing.T) {
+	test := SPRT{Elo0: 0, Elo1: 10, Alpha: 0.05, Beta: 0.05}
+	if lower, upper := test.Bounds(); math.Abs(lower+2.944) > 0.001 || math.Abs(upper-2.944) > 0.001 {
+		t.Errorf("Expected bounds of +/-2.944, got %.3f %.3f", lower, upper)
+	}
+
+	//dead even: should end up accepting H0
+	test.Pentanomial = [5]int{100, 400, 1000, 400, 100}
+	if done, accepted := test.Result(); !done || accepted {
+		t.Errorf("Expected H0 for an even score, LLR %.2f", test.LLR())
+	}
+	//about 35 Elo: should accept H1
+	test.Pentanomial = [5]int{50, 300, 1000, 500, 150}
+	if done, accepted := test.Result(); !done || !accepted {
+		t.Errorf("Expected H1 for a winning score, LLR %.2f", test.LLR())
+	}
+	if diff, _ := test.Elo(); diff < 30 || diff > 40 {
+		t.Errorf("Expected about 35 Elo, got %.1f", diff)
+	}
+}
@@ -0,0 +1,253 @@
+package match
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+)
+
+//Opening is a position to start a game from, with the moves played to reach it
+type Opening struct {
+	FEN   string //"startpos" or a FEN
+	Moves board.MoveList
+}
+
+//reads openings from a PGN file (one opening per game) or an EPD file (one position per line)
+func LoadOpenings(filename string) ([]Opening, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(filename), ".pgn") {
+		return readPGNOpenings(file)
+	}
+	return readEPDOpenings(file)
+}
+
+//EPD lines have the 4 FEN fields the engine needs, then operations. move counters are used if they're
+//there, as in plain FEN.
+func readEPDOpenings(r io.Reader) (openings []Opening, err error) {
+	lines := bufio.NewScanner(r)
+	for lineNumber := 1; lines.Scan(); lineNumber++ {
+		fields := strings.Fields(lines.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("line %d: not enough fields for a position", lineNumber)
+		}
+		fen := strings.Join(fields[:4], " ")
+		if len(fields) >= 6 && isNumber(fields[4]) && isNumber(fields[5]) {
+			fen += " " + fields[4] + " " + fields[5]
+		} else {
+			fen += " 0 1"
+		}
+		if _, err := board.ParseFEN(fen); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		openings = append(openings, Opening{FEN: fen})
+	}
+	return openings, lines.Err()
+}
+
+func isNumber(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
+
+//each game in the file is an opening: the FEN tag (if any) and the moves. comments, variations and
+//annotations are skipped.
+func readPGNOpenings(r io.Reader) (openings []Opening, err error) {
+	var opening *Opening
+	var pos board.Position
+	var comment, variation int //nesting depth of the {comments} and (variations) we're in
+
+	finish := func() {
+		if opening != nil {
+			openings = append(openings, *opening)
+			opening = nil
+		}
+	}
+	start := func(fen string) error {
+		p, err := board.ParseFEN(fen)
+		if err != nil {
+			return err
+		}
+		pos = p
+		opening = &Opening{FEN: fen}
+		return nil
+	}
+
+	lines := bufio.NewScanner(r)
+	for lineNumber := 1; lines.Scan(); lineNumber++ {
+		line := strings.TrimSpace(lines.Text())
+		if comment == 0 && strings.HasPrefix(line, "[") {
+			name, value, ok := parseTag(line)
+			if !ok {
+				return nil, fmt.Errorf("line %d: bad tag: %s", lineNumber, line)
+			}
+			//the tags start a new game
+			if opening != nil && len(opening.Moves) > 0 {
+				finish()
+			}
+			if opening == nil {
+				start("startpos")
+			}
+			if name == "FEN" {
+				if err := start(value); err != nil {
+					return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+				}
+			}
+			continue
+		}
+
+		for _, token := range tokenizeMovetext(line) {
+			switch {
+			case token == "{":
+				comment++
+			case token == "}":
+				comment--
+			case comment > 0:
+			case token == "(":
+				variation++
+			case token == ")":
+				variation--
+			case variation > 0 || strings.HasPrefix(token, "$"):
+			case token == "1-0" || token == "0-1" || token == "1/2-1/2" || token == "*":
+				finish()
+			default:
+				if opening == nil {
+					start("startpos")
+				}
+				m, ok := board.ParseSAN(&pos, token)
+				if !ok {
+					return nil, fmt.Errorf("line %d: illegal move: %s", lineNumber, token)
+				}
+				pos.DoMove(m)
+				opening.Moves = append(opening.Moves, m)
+			}
+		}
+	}
+	finish()
+	if err := lines.Err(); err != nil {
+		return nil, err
+	}
+	if len(openings) == 0 {
+		return nil, errors.New("no games in PGN")
+	}
+	return openings, nil
+}
+
+//reads a tag pair like [White "Aristocrat"]
+func parseTag(line string) (name, value string, ok bool) {
+	if !strings.HasSuffix(line, "]") {
+		return
+	}
+	name, value, ok = strings.Cut(line[1:len(line)-1], " ")
+	value = strings.TrimSpace(value)
+	if !ok || len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return "", "", false
+	}
+	return name, strings.ReplaceAll(value[1:len(value)-1], `\"`, `"`), true
+}
+
+//splits a line of movetext into moves, results, NAGs and the brackets around comments and variations.
+//move numbers and anything after a ; are dropped.
+func tokenizeMovetext(line string) (tokens []string) {
+	if i := strings.Index(line, ";"); i >= 0 {
+		line = line[:i]
+	}
+	for _, bracket := range []string{"{", "}", "(", ")"} {
+		line = strings.ReplaceAll(line, bracket, " "+bracket+" ")
+	}
+	for _, token := range strings.Fields(line) {
+		//move numbers: 1. or 1... possibly stuck to the move
+		if i := strings.LastIndex(token, "."); i >= 0 && isNumber(strings.TrimRight(token[:i+1], ".")) {
+			token = token[i+1:]
+		}
+		if token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return
+}
+
+//the Variant tag for each variant, as lichess writes them
+var pgnVariantNames = [board.NUMVARIANTS]string{"Standard", "Three-check", "King of the Hill", "Antichess", "Atomic", "Crazyhouse"}
+
+//writes the game in PGN
+func WritePGN(w io.Writer, g Game) error {
+	tags := [][2]string{
+		{"Event", "Aristocrat match"},
+		{"Site", "?"},
+		{"Date", g.Date.Format("2006.01.02")},
+		{"Round", fmt.Sprint(g.Round)},
+		{"White", g.White},
+		{"Black", g.Black},
+		{"Result", g.Result},
+	}
+	pos := board.NewPosition(g.StartFEN)
+	if pos.Variant != board.STANDARD {
+		tags = append(tags, [2]string{"Variant", pgnVariantNames[pos.Variant]})
+	} else if pos.Chess960 {
+		tags = append(tags, [2]string{"Variant", "Chess960"})
+	}
+	if g.StartFEN != "startpos" {
+		tags = append(tags, [2]string{"FEN", g.StartFEN}, [2]string{"SetUp", "1"})
+	}
+	tags = append(tags, [2]string{"TimeControl", g.TimeControl.String()}, [2]string{"Termination", g.Termination}, [2]string{"PlyCount", fmt.Sprint(len(g.Moves))})
+
+	var out strings.Builder
+	for _, tag := range tags {
+		fmt.Fprintf(&out, "[%s \"%s\"]\n", tag[0], strings.ReplaceAll(tag[1], `"`, `\"`))
+	}
+	out.WriteString("\n")
+
+	//the move numbers depend on where the game started
+	moveNumber, toMove := pos.FullMoveCounter, pos.ToMove
+	var tokens []string
+	for i, san := range g.Moves {
+		if toMove == board.WHITE {
+			tokens = append(tokens, fmt.Sprintf("%d.", moveNumber))
+		} else if i == 0 {
+			tokens = append(tokens, fmt.Sprintf("%d...", moveNumber))
+		}
+		tokens = append(tokens, san)
+		if i+1 == g.OpeningMoves {
+			tokens = append(tokens, "{book}")
+		}
+		if toMove == board.BLACK {
+			moveNumber++
+		}
+		toMove = board.Opponent(toMove)
+	}
+	if g.Reason != "" {
+		tokens = append(tokens, "{"+g.Reason+"}")
+	}
+	tokens = append(tokens, g.Result)
+
+	//lines are kept under 80 characters, as the standard says
+	lineLength := 0
+	for _, token := range tokens {
+		if lineLength > 0 && lineLength+1+len(token) > 79 {
+			out.WriteString("\n")
+			lineLength = 0
+		} else if lineLength > 0 {
+			out.WriteString(" ")
+			lineLength++
+		}
+		out.WriteString(token)
+		lineLength += len(token)
+	}
+	out.WriteString("\n\n")
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
@@ -0,0 +1,281 @@
+package match
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+	"github.com/BenNicholls/Aristocrat2/search"
+)
+
+//score players report for a mate, from their point of view. mate in n is reported as MATESCORE - n.
+const MATESCORE int = 100000
+
+//how long a UCI engine gets to start up and answer uci/isready
+const UCITIMEOUT = 10 * time.Second
+
+var errTimeout = errors.New("ran out of time")
+
+//Request is what a player gets asked to move in
+type Request struct {
+	StartFEN  string          //"startpos" or a FEN
+	Moves     board.MoveList  //played since the start position
+	Position  *board.Position //after Moves
+	Time      [2]int          //msec left on each side's clock
+	Increment int             //msec
+	MovesToGo int             //until the next time control, 0 if there isn't one
+	MoveTime  int             //msec per move. if set, the clocks aren't used
+	Depth     int             //plies to search. if set, there's no time limit
+}
+
+//how long the side to move has before it loses on time. 0 if there's no limit.
+func (r Request) timeLeft() time.Duration {
+	if r.Depth > 0 {
+		return 0
+	} else if r.MoveTime > 0 {
+		return time.Duration(r.MoveTime)*time.Millisecond + TIMEMARGIN
+	}
+	return time.Duration(r.Time[r.Position.ToMove])*time.Millisecond + TIMEMARGIN
+}
+
+//Player is one side of a match: something that picks moves.
+type Player interface {
+	Name() string
+	NewGame() error
+	//returns the move to play in UCI notation, and the player's last score for the position in
+	//centipawns from its own point of view
+	Move(r Request) (move string, score int, err error)
+	Close() error
+}
+
+//UCIPlayer runs a UCI engine as a subprocess
+type UCIPlayer struct {
+	name   string
+	cmd    *exec.Cmd
+	input  io.WriteCloser
+	output chan string //lines from the engine. closed when it exits
+
+	chess960 bool          //whether UCI_Chess960 is on
+	variant  board.Variant //what UCI_Variant is set to
+}
+
+//starts the engine at path and sets the given UCI options on it
+func NewUCIPlayer(path string, options map[string]string) (*UCIPlayer, error) {
+	cmd := exec.Command(path)
+	input, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	output, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	u := &UCIPlayer{name: filepath.Base(path), cmd: cmd, input: input, output: make(chan string, 100)}
+	go func() {
+		lines := bufio.NewScanner(output)
+		for lines.Scan() {
+			u.output <- lines.Text()
+		}
+		close(u.output)
+	}()
+
+	u.send("uci")
+	err = u.waitFor("uciok", UCITIMEOUT, func(line string) {
+		if name, ok := strings.CutPrefix(line, "id name "); ok {
+			u.name = strings.TrimSpace(name)
+		}
+	})
+	if err != nil {
+		u.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for name, value := range options {
+		u.send("setoption name " + name + " value " + value)
+	}
+	if err := u.NewGame(); err != nil {
+		u.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return u, nil
+}
+
+func (u *UCIPlayer) Name() string {
+	return u.name
+}
+
+func (u *UCIPlayer) send(command string) {
+	fmt.Fprintln(u.input, command)
+}
+
+//reads output until a line starting with prefix, passing everything before it to each. each can be nil,
+//and a timeout of 0 waits as long as it takes.
+func (u *UCIPlayer) waitFor(prefix string, timeout time.Duration, each func(line string)) error {
+	_, err := u.waitForLine(prefix, timeout, each)
+	return err
+}
+
+func (u *UCIPlayer) waitForLine(prefix string, timeout time.Duration, each func(line string)) (string, error) {
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		deadline = time.After(timeout)
+	}
+	for {
+		select {
+		case line, ok := <-u.output:
+			if !ok {
+				return "", errors.New("engine exited")
+			}
+			if strings.HasPrefix(line, prefix) {
+				return line, nil
+			}
+			if each != nil {
+				each(line)
+			}
+		case <-deadline:
+			return "", errTimeout
+		}
+	}
+}
+
+func (u *UCIPlayer) NewGame() error {
+	u.send("ucinewgame")
+	u.send("isready")
+	return u.waitFor("readyok", UCITIMEOUT, nil)
+}
+
+func (u *UCIPlayer) Move(r Request) (move string, score int, err error) {
+	position := "position startpos"
+	if r.StartFEN != "startpos" {
+		position = "position fen " + r.StartFEN
+	}
+	if len(r.Moves) > 0 {
+		position += " moves " + r.Moves.UCIvariation()
+	}
+	if r.Position.Chess960 != u.chess960 {
+		u.chess960 = r.Position.Chess960
+		u.send(fmt.Sprintf("setoption name UCI_Chess960 value %t", u.chess960))
+	}
+	if r.Position.Variant != u.variant {
+		u.variant = r.Position.Variant
+		u.send("setoption name UCI_Variant value " + u.variant.String())
+	}
+	u.send(position)
+	if r.Depth > 0 {
+		u.send(fmt.Sprintf("go depth %d", r.Depth))
+	} else if r.MoveTime > 0 {
+		u.send(fmt.Sprintf("go movetime %d", r.MoveTime))
+	} else {
+		goCommand := fmt.Sprintf("go wtime %d btime %d winc %d binc %d", r.Time[board.WHITE], r.Time[board.BLACK], r.Increment, r.Increment)
+		if r.MovesToGo > 0 {
+			goCommand += fmt.Sprintf(" movestogo %d", r.MovesToGo)
+		}
+		u.send(goCommand)
+	}
+
+	readScore := func(line string) {
+		if s, ok := parseScore(line); ok {
+			score = s
+		}
+	}
+	line, err := u.waitForLine("bestmove", r.timeLeft(), readScore)
+	if err == errTimeout {
+		//it's lost anyway, but get it to stop so it's ready for the next game
+		u.send("stop")
+		u.waitFor("bestmove", time.Second, nil)
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	if fields := strings.Fields(line); len(fields) > 1 {
+		move = fields[1]
+	}
+	return move, score, nil
+}
+
+//reads the score from a UCI info line, if it has one
+func parseScore(line string) (score int, ok bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || fields[0] != "info" {
+		return
+	}
+	for i := 1; i+2 < len(fields); i++ {
+		if fields[i] != "score" {
+			continue
+		}
+		n, err := strconv.Atoi(fields[i+2])
+		if err != nil {
+			return 0, false
+		}
+		switch fields[i+1] {
+		case "cp":
+			return n, true
+		case "mate":
+			if n < 0 {
+				return -MATESCORE - n, true
+			}
+			return MATESCORE - n, true
+		}
+	}
+	return
+}
+
+//asks the engine to quit, and kills it if it doesn't
+func (u *UCIPlayer) Close() error {
+	u.send("quit")
+	u.input.Close()
+	exited := make(chan error, 1)
+	go func() {
+		exited <- u.cmd.Wait()
+	}()
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(UCITIMEOUT):
+		u.cmd.Process.Kill()
+		return <-exited
+	}
+}
+
+//EnginePlayer plays with a search engine in this process
+type EnginePlayer struct {
+	engine *search.Engine
+}
+
+func NewEnginePlayer(engine *search.Engine) *EnginePlayer {
+	return &EnginePlayer{engine}
+}
+
+func (e *EnginePlayer) Name() string {
+	return "Aristocrat"
+}
+
+func (e *EnginePlayer) NewGame() error {
+	return nil
+}
+
+func (e *EnginePlayer) Move(r Request) (move string, score int, err error) {
+	limits := search.SearchLimits{MultiPV: 1, MoveTime: r.MoveTime, Depth: r.Depth}
+	if r.MoveTime == 0 && r.Depth == 0 {
+		limits.Time, limits.Increment, limits.MovesToGo = r.Time[r.Position.ToMove], r.Increment, r.MovesToGo
+	}
+	lines, _ := e.engine.Search(r.Position, limits, nil)
+	if len(lines) == 0 || len(lines[0].Variation) == 0 {
+		return "", 0, errors.New("no move found")
+	}
+	return lines[0].Variation[0].UCIstring(), lines[0].Score * board.ScoreModifier[r.Position.ToMove], nil
+}
+
+func (e *EnginePlayer) Close() error {
+	return nil
+}
@@ -0,0 +1,81 @@
+package match
+
+import "math"
+
+//SPRT is a sequential probability ratio test of the hypotheses that the first player is Elo0 (H0) or
+//Elo1 (H1) stronger than the second. games are counted in pairs (the same opening with each colour) to
+//cancel out unbalanced openings, using the pentanomial model: each pair scores 0, 0.5, 1, 1.5 or 2.
+type SPRT struct {
+	Elo0, Elo1  float64
+	Alpha, Beta float64 //chances of accepting H1 when H0 is true, and H0 when H1 is true
+	Pentanomial [5]int  //pairs by the first player's points in the pair, in half points
+}
+
+//counts a pair of games by the first player's points in it
+func (s *SPRT) Add(points float64) {
+	s.Pentanomial[int(math.Round(points*2))]++
+}
+
+func (s SPRT) Pairs() (n int) {
+	for _, count := range s.Pentanomial {
+		n += count
+	}
+	return
+}
+
+//LLR bounds for accepting H0 (lower) and H1 (upper)
+func (s SPRT) Bounds() (lower, upper float64) {
+	return math.Log(s.Beta / (1 - s.Alpha)), math.Log((1 - s.Beta) / s.Alpha)
+}
+
+//mean and variance of the score per game, from the pairs
+func (s SPRT) scoreStats() (mean, variance float64) {
+	n := float64(s.Pairs())
+	for i, count := range s.Pentanomial {
+		mean += float64(count) * float64(i) / 4
+	}
+	mean /= n
+	for i, count := range s.Pentanomial {
+		variance += float64(count) * math.Pow(float64(i)/4-mean, 2)
+	}
+	return mean, variance / n
+}
+
+//LLR is the log likelihood ratio of H1 over H0 so far. uses the normal approximation, which is plenty
+//accurate for the number of games an SPRT takes.
+func (s SPRT) LLR() float64 {
+	if s.Pairs() < 2 {
+		return 0
+	}
+	mean, variance := s.scoreStats()
+	if variance == 0 { //every pair the same so far. nothing to go on yet
+		return 0
+	}
+	s0, s1 := expectedScore(s.Elo0), expectedScore(s.Elo1)
+	return float64(s.Pairs()) * (s1 - s0) * (2*mean - s0 - s1) / (2 * variance)
+}
+
+//reports whether the test is over, and if so whether H1 (the first player is Elo1 stronger) was accepted
+func (s SPRT) Result() (done, accepted bool) {
+	lower, upper := s.Bounds()
+	llr := s.LLR()
+	return llr <= lower || llr >= upper, llr >= upper
+}
+
+//Elo estimates the rating difference and its 95% confidence margin from the pairs
+func (s SPRT) Elo() (diff, margin float64) {
+	if s.Pairs() == 0 {
+		return 0, math.Inf(1)
+	}
+	mean, variance := s.scoreStats()
+	if mean == 0 || mean == 1 {
+		return eloDifference(mean), math.Inf(1)
+	}
+	deviation := 1.96 * math.Sqrt(variance/float64(s.Pairs()))
+	return eloDifference(mean), (eloDifference(mean+deviation) - eloDifference(mean-deviation)) / 2
+}
+
+//the score expected against a player elo weaker
+func expectedScore(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
@@ -0,0 +1,263 @@
+//Package nnue is an efficiently updatable neural network evaluation. A HalfKP feature transformer turns
+//the pieces into a hidden layer for each side, which positions keep up to date move by move in an
+//Accumulator; small quantized dense layers then turn the two halves into a score. Everything is integer
+//maths on the CPU.
+//
+//Colours, pieces and squares are numbered as in package board: WHITE 0, BLACK 1, PAWN 0 through KING 5,
+//and square 0 is a8.
+package nnue
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+)
+
+//network shape: 40960 -> 256x2 -> 32 -> 32 -> 1
+const (
+	FEATURES = 64 * 10 * 64 //king square x non-king piece (by colour) x square
+	HIDDEN   = 256          //accumulator size, for each side
+	L1       = 32
+	L2       = 32
+)
+
+//quantization. the accumulator and activations are clipped to [0, 127]; dense weights are scaled by 64,
+//so each layer's output is shifted back down by 6 bits. the final output is 16 times centipawns.
+const (
+	ACTIVATIONMAX   = 127
+	WEIGHTSCALEBITS = 6
+	OUTPUTSCALE     = 16
+)
+
+//what network files start with, then the version
+const (
+	MAGIC   = "ARINNUE\x00"
+	VERSION = 1
+)
+
+const king = 5
+
+//dense layer with int8 weights and int32 biases. weights are stored one output at a time.
+type layer struct {
+	biases  []int32
+	weights []int8
+}
+
+func newLayer(in, out int) layer {
+	return layer{make([]int32, out), make([]int8, in*out)}
+}
+
+//computes the layer's outputs, clipped to [0, 127]
+func (l *layer) forward(in []int8, out []int8) {
+	for o := range out {
+		sum := l.biases[o]
+		row := l.weights[o*len(in) : (o+1)*len(in)]
+		for i, x := range in {
+			sum += int32(x) * int32(row[i])
+		}
+		out[o] = clip(sum >> WEIGHTSCALEBITS)
+	}
+}
+
+func clip(x int32) int8 {
+	return int8(min(max(x, 0), ACTIVATIONMAX))
+}
+
+//Network is the parameters of a net
+type Network struct {
+	featureBiases  [HIDDEN]int16
+	featureWeights []int16 //FEATURES rows of HIDDEN
+	hidden1        layer
+	hidden2        layer
+	output         layer
+}
+
+func newNetwork() *Network {
+	return &Network{
+		featureWeights: make([]int16, FEATURES*HIDDEN),
+		hidden1:        newLayer(2*HIDDEN, L1),
+		hidden2:        newLayer(L1, L2),
+		output:         newLayer(L2, 1),
+	}
+}
+
+//reads a network file. networks don't change once they're read, so any number of evaluators can share one.
+func Load(filename string) (*Network, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	n, err := Read(bufio.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return n, nil
+}
+
+//reads a network in the format WriteTo writes: the magic and version, then the feature transformer's
+//biases and weights, then the biases and weights of each dense layer, all little endian.
+func Read(r io.Reader) (*Network, error) {
+	magic := make([]byte, len(MAGIC))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != MAGIC {
+		return nil, errors.New("not a network file")
+	}
+	var version uint32
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return nil, err
+	} else if version != VERSION {
+		return nil, fmt.Errorf("network version %d, expected %d", version, VERSION)
+	}
+
+	n := newNetwork()
+	for _, data := range n.parameters() {
+		if err := binary.Read(r, binary.LittleEndian, data); err != nil {
+			return nil, fmt.Errorf("network file too short: %w", err)
+		}
+	}
+	return n, nil
+}
+
+//all the parameters in file order
+func (n *Network) parameters() []any {
+	return []any{
+		n.featureBiases[:], n.featureWeights,
+		n.hidden1.biases, n.hidden1.weights,
+		n.hidden2.biases, n.hidden2.weights,
+		n.output.biases, n.output.weights,
+	}
+}
+
+func (n *Network) WriteTo(w io.Writer) (written int64, err error) {
+	out := &countingWriter{w: w}
+	if _, err = io.WriteString(out, MAGIC); err != nil {
+		return out.n, err
+	}
+	if err = binary.Write(out, binary.LittleEndian, uint32(VERSION)); err != nil {
+		return out.n, err
+	}
+	for _, data := range n.parameters() {
+		if err = binary.Write(out, binary.LittleEndian, data); err != nil {
+			break
+		}
+	}
+	return out.n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (n int, err error) {
+	n, err = c.w.Write(b)
+	c.n += int64(n)
+	return
+}
+
+//RandomNetwork makes a network with small random parameters. it plays terribly, but it's a starting
+//point for training and exercises everything else.
+func RandomNetwork(seed int64) *Network {
+	rng := rand.New(rand.NewSource(seed))
+	n := newNetwork()
+	for i := range n.featureBiases {
+		n.featureBiases[i] = int16(rng.Intn(64))
+	}
+	for i := range n.featureWeights {
+		n.featureWeights[i] = int16(rng.Intn(33) - 16)
+	}
+	for _, l := range []*layer{&n.hidden1, &n.hidden2, &n.output} {
+		for i := range l.biases {
+			l.biases[i] = int32(rng.Intn(1024) - 512)
+		}
+		for i := range l.weights {
+			l.weights[i] = int8(rng.Intn(33) - 16)
+		}
+	}
+	return n
+}
+
+//index of the feature for a piece, seen from one side with its king on kingSquare. black sees the board
+//upside down, and its own pieces as "ours", so both sides share the weights.
+func featureIndex(perspective, kingSquare, colour, piece, square int) int {
+	if perspective == 1 {
+		kingSquare ^= 56
+		square ^= 56
+	}
+	pieceIndex := piece * 2
+	if colour != perspective {
+		pieceIndex++
+	}
+	return (kingSquare*10+pieceIndex)*64 + square
+}
+
+//Accumulator is the feature transformer's output for a position, for each side. it can be updated as
+//pieces come and go, so the expensive first layer is only computed in full when a king moves.
+type Accumulator struct {
+	network    *Network //the network the values are for
+	kingSquare [2]int
+	values     [2][HIDDEN]int16
+}
+
+//starts one side over with just the biases, for the network. add all the pieces after.
+func (a *Accumulator) Reset(n *Network, perspective, kingSquare int) {
+	a.network = n
+	a.kingSquare[perspective] = kingSquare
+	a.values[perspective] = n.featureBiases
+}
+
+//adds a piece for one side. kings aren't features: when one moves, its side has to be Reset.
+func (a *Accumulator) AddFeature(perspective, colour, piece, square int) {
+	if piece == king {
+		return
+	}
+	weights := a.featureWeights(perspective, colour, piece, square)
+	values := &a.values[perspective]
+	for i := range values {
+		values[i] += weights[i]
+	}
+}
+
+func (a *Accumulator) RemoveFeature(perspective, colour, piece, square int) {
+	if piece == king {
+		return
+	}
+	weights := a.featureWeights(perspective, colour, piece, square)
+	values := &a.values[perspective]
+	for i := range values {
+		values[i] -= weights[i]
+	}
+}
+
+func (a *Accumulator) featureWeights(perspective, colour, piece, square int) []int16 {
+	index := featureIndex(perspective, a.kingSquare[perspective], colour, piece, square)
+	return a.network.featureWeights[index*HIDDEN : (index+1)*HIDDEN]
+}
+
+//Evaluate runs the rest of the network on an accumulator. returns centipawns from the point of
+//view of the side to move.
+func Evaluate(a *Accumulator, toMove int) int {
+	n := a.network
+	var input [2 * HIDDEN]int8
+	for i, perspective := range [2]int{toMove, 1 - toMove} {
+		for j, v := range a.values[perspective] {
+			input[i*HIDDEN+j] = clip(int32(v))
+		}
+	}
+
+	var hidden1 [L1]int8
+	var hidden2 [L2]int8
+	n.hidden1.forward(input[:], hidden1[:])
+	n.hidden2.forward(hidden1[:], hidden2[:])
+
+	output := n.output.biases[0]
+	for i, x := range hidden2 {
+		output += int32(x) * int32(n.output.weights[i])
+	}
+	return int(output) / OUTPUTSCALE
+}
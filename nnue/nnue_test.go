@@ -0,0 +1,26 @@
+package nnue
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestReadWrite(t *testing.T) {
+	n := RandomNetwork(1)
+	var file bytes.Buffer
+	if _, err := n.WriteTo(&file); err != nil {
+		t.Fatal(err)
+	}
+	read, err := Read(bytes.NewReader(file.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(n, read) {
+		t.Error("Network read back differently")
+	}
+
+	if _, err := Read(bytes.NewReader(file.Bytes()[:file.Len()-1])); err == nil {
+		t.Error("Truncated network read without error")
+	}
+}
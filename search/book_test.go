@@ -0,0 +1,128 @@
+package search
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+)
+
+//reference keys from the polyglot book format specification
+func TestPolyglotKey(t *testing.T) {
+	tests := []struct {
+		moves []string
+		key   uint64
+	}{
+		{[]string{}, 0x463b96181691fc9c},
+		{[]string{"e2e4"}, 0x823c9b50fd114196},
+		{[]string{"e2e4", "d7d5"}, 0x0756b94461c50fb0},
+		{[]string{"e2e4", "d7d5", "e4e5"}, 0x662fafb965db29d4},
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5"}, 0x22a48b5a8e47ff78},
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5", "e1e2"}, 0x652a607ca3f242c1},
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5", "e1e2", "e8f7"}, 0x00fdd303c946bdd9},
+		{[]string{"a2a4", "b7b5", "h2h4", "b5b4", "c2c4"}, 0x3c8123ea7b067637},
+		{[]string{"a2a4", "b7b5", "h2h4", "b5b4", "c2c4", "b4c3", "a1a3"}, 0x5c3f9b829b279560},
+	}
+
+	for _, test := range tests {
+		pos := board.NewPosition("")
+		for _, ms := range test.moves {
+			from, to := board.AlgebraicToSquare(ms[:2]), board.AlgebraicToSquare(ms[2:4])
+			list, _ := board.Movegen(&pos)
+			for _, m := range list {
+				if m.From() == from && m.To() == to {
+					pos.DoMove(m)
+					break
+				}
+			}
+		}
+		if key := polyglotKey(&pos); key != test.key {
+			t.Errorf("Polyglot key after %v: expected %016x, got %016x", test.moves, test.key, key)
+		}
+	}
+}
+
+//a polyglot move from UCI-style squares: rows count up from rank 1, and promotion pieces go from 1 for a
+//knight to 4 for a queen
+func polyglotMove(from, to string, promote int) uint16 {
+	square := func(s string) uint16 {
+		return uint16(s[0]-'a') | uint16(s[1]-'1')<<3
+	}
+	return square(to) | square(from)<<6 | uint16(promote)<<12
+}
+
+//a tiny book, written out the way polyglot does: entries sorted by key, big-endian
+func writeBook(t *testing.T, entries []bookEntry) string {
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+	data := make([]byte, 0, len(entries)*int(POLYGLOTENTRYSIZE))
+	for _, e := range entries {
+		data = binary.BigEndian.AppendUint64(data, e.key)
+		data = binary.BigEndian.AppendUint16(data, e.move)
+		data = binary.BigEndian.AppendUint16(data, e.weight)
+		data = binary.BigEndian.AppendUint32(data, 0)
+	}
+	filename := filepath.Join(t.TempDir(), "book.bin")
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestBook(t *testing.T) {
+	start := board.NewPosition("")
+	castling := board.NewPosition("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	promotion := board.NewPosition("8/P6k/8/8/8/8/8/K7 w - - 0 1")
+	book, err := openBook(writeBook(t, []bookEntry{
+		{polyglotKey(&start), polyglotMove("e2", "e4", 0), 3},
+		{polyglotKey(&start), polyglotMove("d2", "d4", 0), 1},
+		{polyglotKey(&start), polyglotMove("e2", "e5", 0), 100},  //not a legal move, so it's never played
+		{polyglotKey(&castling), polyglotMove("e1", "h1", 0), 1}, //castling is the king taking its own rook
+		{polyglotKey(&castling), polyglotMove("e1", "a1", 0), 1},
+		{polyglotKey(&promotion), polyglotMove("a7", "a8", 1), 1},
+		{polyglotKey(&promotion), polyglotMove("a7", "a8", 4), 1},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer book.close()
+
+	for _, test := range []struct {
+		pos      board.Position
+		expected []string
+	}{
+		{start, []string{"e2e4", "d2d4"}},
+		{castling, []string{"e1g1", "e1c1"}},
+		{promotion, []string{"a7a8n", "a7a8q"}},
+	} {
+		found, _ := book.lookup(&test.pos)
+		if len(found) != len(test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.pos.FEN(), test.expected, found)
+			continue
+		}
+		for i, m := range found {
+			if m.UCIstring() != test.expected[i] {
+				t.Errorf("%s: expected %v, got %v", test.pos.FEN(), test.expected, found)
+			}
+		}
+	}
+
+	//e4 has three times the weight of d4
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		m, ok := book.probe(&start)
+		if !ok {
+			t.Fatal("No book move for the start position")
+		}
+		counts[m.UCIstring()]++
+	}
+	if len(counts) != 2 || counts["e2e4"] < 2800 || counts["e2e4"] > 3200 {
+		t.Errorf("Expected e2e4 about 3000 times in 4000 and d2d4 the rest, got %v", counts)
+	}
+	empty := board.NewPosition("4k3/8/8/8/8/8/8/4K3 w - - 0 1")
+	if m, ok := book.probe(&empty); ok {
+		t.Errorf("Position not in the book gave %s", m.UCIstring())
+	}
+}
@@ -0,0 +1,180 @@
+//Package search finds moves. each Engine has its own hashtable, book, tablebases and evaluation, so
+//several can run in the same program without getting in each other's way.
+package search
+
+import (
+	"sync"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+	"github.com/BenNicholls/Aristocrat2/eval"
+	"github.com/BenNicholls/Aristocrat2/nnue"
+)
+
+//Engine searches positions. each engine has its own hashtable, controller, book, tablebases and
+//evaluator, so any number of them can run independently in one program. make them with NewEngine.
+type Engine struct {
+	//options. change them between searches, not during.
+	Threads   int  //number of search threads
+	MultiPV   int  //number of lines to search for, unless the search limits say otherwise
+	OwnBook   bool //play moves from the book, if one is loaded
+	BookDepth int  //max full move number to use the book for
+
+	table      *hashTable
+	hashSize   int        //MB, for the perft table
+	perftTable *hashTable //made the first time perft needs it. see PerftCache
+	perftLock  sync.Mutex
+	controller calculationController
+	book       *openingBook
+	tablebases tbRegistry
+	evaluator  *eval.Evaluator
+	tbHits     int64 //successful probes during the current search. access atomically.
+
+	searchLock  sync.Mutex     //held for the whole of a search. one at a time per engine
+	multiPV     int            //for the current search
+	searchMoves board.MoveList //for the current search. if set, only these moves are searched at the root
+}
+
+//hashtable size for new engines, in MB
+const DEFAULTHASHSIZE int = 2
+
+type finishedSearch struct {
+	lines []Line
+	stats SearchStats
+}
+
+func NewEngine() *Engine {
+	return &Engine{Threads: 1, MultiPV: 1, BookDepth: 20, table: newHashTable(DEFAULTHASHSIZE), hashSize: DEFAULTHASHSIZE, evaluator: eval.NewEvaluator()}
+}
+
+//the evaluation the engine searches with
+func (e *Engine) Evaluator() *eval.Evaluator {
+	return e.evaluator
+}
+
+//searches with a copy of the evaluator, so engines can start out with the same settings and then
+//change their own
+func (e *Engine) SetEvaluator(ev *eval.Evaluator) {
+	copy := *ev
+	e.evaluator = &copy
+}
+
+//reads the evaluation weights from a file. an empty filename goes back to the default weights.
+func (e *Engine) LoadWeights(filename string) error {
+	if filename == "" {
+		e.evaluator.SetWeights(eval.DefaultWeights)
+		return nil
+	}
+	w, err := eval.LoadWeights(filename)
+	if err != nil {
+		return err
+	}
+	e.evaluator.SetWeights(w)
+	return nil
+}
+
+//reads an nnue network to evaluate with. an empty filename goes back to the classical evaluation.
+func (e *Engine) LoadNetwork(filename string) error {
+	if filename == "" {
+		e.evaluator.SetNetwork(nil)
+		return nil
+	}
+	n, err := nnue.Load(filename)
+	if err != nil {
+		return err
+	}
+	e.evaluator.SetNetwork(n)
+	return nil
+}
+
+//replaces the hashtable with an empty one of the given size in MB. 0 turns the hashtable off.
+func (e *Engine) SetHashSize(size int) {
+	e.table = newHashTable(size)
+	e.hashSize = size
+	e.perftLock.Lock()
+	e.perftTable = nil
+	e.perftLock.Unlock()
+}
+
+//a cache for perft, the same size as the hashtable. it's a table of its own: perft's node counts would
+//look like exact scores to the search.
+func (e *Engine) PerftCache() board.PerftCache {
+	e.perftLock.Lock()
+	defer e.perftLock.Unlock()
+	if e.perftTable == nil {
+		e.perftTable = newHashTable(e.hashSize)
+	}
+	return e.perftTable
+}
+
+//Search searches the position within the limits and returns the lines found, best first. it blocks
+//until the search is done; see Start for searching in the background. reporter can be nil if only the
+//result is needed.
+func (e *Engine) Search(pos *board.Position, limits SearchLimits, reporter SearchReporter) ([]Line, SearchStats) {
+	result := <-e.start(pos, limits, reporter)
+	return result.lines, result.stats
+}
+
+//Start begins searching the position in the background, and sends the lines found on the returned
+//channel once it's done. the search is under way by the time Start returns, so it can be stopped or
+//ponderhit straight away.
+func (e *Engine) Start(pos *board.Position, limits SearchLimits, reporter SearchReporter) <-chan []Line {
+	lines := make(chan []Line, 1)
+	done := e.start(pos, limits, reporter)
+	go func() {
+		lines <- (<-done).lines
+	}()
+	return lines
+}
+
+//Analyse is Search with the result packaged up for passing on, ie. as JSON.
+func (e *Engine) Analyse(pos *board.Position, limits SearchLimits, reporter SearchReporter) SearchResult {
+	lines, stats := e.Search(pos, limits, reporter)
+	return newSearchResult(lines, stats)
+}
+
+//stops the running search. it still reports its result as normal.
+func (e *Engine) Stop() {
+	e.controller.stopCalculators()
+}
+
+//the opponent played the move we were pondering on. the search carries on as a normal timed search.
+func (e *Engine) PonderHit() {
+	e.controller.ponderhit()
+}
+
+//reports whether a search is running
+func (e *Engine) Calculating() bool {
+	return e.controller.calculating()
+}
+
+//sets up the controller and launches the search on a copy of the position, so the caller can carry
+//on using theirs.
+func (e *Engine) start(pos *board.Position, limits SearchLimits, reporter SearchReporter) <-chan finishedSearch {
+	if reporter == nil {
+		reporter = &SearchCollector{}
+	}
+	depth := limits.Depth
+	if depth == 0 {
+		depth = 8
+		if limits.MoveTime != 0 || limits.Nodes != 0 || limits.MateIn != 0 || limits.Time != 0 || limits.Infinite || limits.Ponder {
+			depth = 100
+		}
+	}
+
+	e.searchLock.Lock()
+	e.multiPV, e.searchMoves = limits.MultiPV, limits.Moves
+	if e.multiPV < 1 {
+		e.multiPV = max(e.MultiPV, 1)
+	}
+	e.controller.setLimits(limits)
+	e.controller.beginCalculating()
+	p := pos.Copy()
+
+	done := make(chan finishedSearch, 1)
+	go func() {
+		lines, stats := e.iterativeSearch(&p, depth, reporter)
+		e.searchLock.Unlock()
+		done <- finishedSearch{lines, stats}
+	}()
+	return done
+}
@@ -0,0 +1,89 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+)
+
+//engines shouldn't share anything: one can search while another is stopped or running its own search
+func TestIndependentEngines(t *testing.T) {
+	a, b := NewEngine(), NewEngine()
+	start := board.NewPosition("")
+	mate := board.NewPosition("6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1")
+
+	done := a.Start(&start, SearchLimits{Infinite: true}, nil)
+	if result := b.Analyse(&mate, SearchLimits{Depth: 3}, nil); result.BestMove != "a1a8" {
+		t.Errorf("Expected bestmove a1a8 while another engine searches, got %s", result.BestMove)
+	}
+	if b.Calculating() || !a.Calculating() {
+		t.Error("Finishing one engine's search affected the other")
+	}
+
+	a.Stop()
+	select {
+	case lines := <-done:
+		if len(lines) == 0 || len(lines[0].Variation) == 0 {
+			t.Error("Stopped search found no move")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Engine didn't stop")
+	}
+}
+
+//winning by a variant's rules scores as a mate
+func TestVariantWins(t *testing.T) {
+	e := NewEngine()
+	threeCheck := board.NewPosition("4k3/8/8/8/8/8/8/4K2R w - - 1+3 0 1")
+	hill := board.NewPosition("8/8/8/8/8/2K5/8/4k3 w - - 0 1")
+	hill.SetVariant(board.KINGOFTHEHILL)
+	anti := board.NewPosition("8/8/8/8/8/8/1p6/R7 w - - 0 1") //the pawn has to take the rook
+	anti.SetVariant(board.ANTICHESS)
+	atomic := board.NewPosition("1k6/1p6/8/8/8/8/8/KR6 w - - 0 1") //taking the pawn blows up the king
+	atomic.SetVariant(board.ATOMIC)
+	crazyhouse := board.NewPosition("6rk/6pp/8/8/8/8/8/K7[N] w - - 0 1") //smothered mate by a drop
+
+	for _, test := range []struct {
+		pos  board.Position
+		best string
+	}{{threeCheck, "h1h8"}, {hill, "c3d4"}, {anti, "a1c1"}, {atomic, "b1b7"}, {crazyhouse, "N@f7"}} {
+		lines, _ := e.Search(&test.pos, SearchLimits{Depth: 3, MultiPV: 1}, nil)
+		if len(lines) == 0 {
+			t.Fatalf("%s (%s): no lines", test.pos.FEN(), test.pos.Variant)
+		}
+		if n, _ := MateIn(lines[0].Score); lines[0].Variation[0].UCIstring() != test.best || n != 1 {
+			t.Errorf("%s (%s): expected %s winning, got %v", test.pos.FEN(), test.pos.Variant, test.best, lines)
+		}
+	}
+}
+
+//mate scores count the plies to the mate, from the side to move's point of view
+func TestMateDistance(t *testing.T) {
+	e := NewEngine()
+	pos := board.NewPosition("k7/8/2K5/8/8/8/8/7R w - - 0 1")
+	lines, _ := e.Search(&pos, SearchLimits{Depth: 5, MultiPV: 1}, nil)
+	if n, ok := MateIn(lines[0].Score); !ok || n != 2 || lines[0].Score != MATE-3 {
+		t.Errorf("Expected mate in 2, got score %d (mate %d)", lines[0].Score, n)
+	}
+
+	pos = board.NewPosition("k7/8/1K6/8/8/8/8/7R b - - 1 1")
+	lines, _ = e.Search(&pos, SearchLimits{Depth: 5, MultiPV: 1}, nil)
+	if n, ok := MateIn(lines[0].Score * board.ScoreModifier[pos.ToMove]); !ok || n != -1 {
+		t.Errorf("Expected to be mated in 1, got score %d (mate %d)", lines[0].Score, n)
+	}
+}
+
+//a mate search only reports mates as short as it was asked for, even if the hashtable knows a longer one
+func TestMateSearch(t *testing.T) {
+	e := NewEngine()
+	pos := board.NewPosition("k7/8/2K5/8/8/8/8/7R w - - 0 1") //mate in 2, but not in 1
+	e.Search(&pos, SearchLimits{Depth: 5}, nil)
+	for mateIn, expected := range map[int]string{1: "no mate in 1", 2: "mate in 2 found"} {
+		reporter := &SearchCollector{}
+		e.Search(&pos, SearchLimits{MateIn: mateIn}, reporter)
+		if len(reporter.Messages) != 1 || reporter.Messages[0] != expected {
+			t.Errorf("Looking for mate in %d said %q, expected %q", mateIn, reporter.Messages, expected)
+		}
+	}
+}
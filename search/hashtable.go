@@ -1,6 +1,10 @@
-package main
+package search
 
-import "sync"
+import (
+	"sync"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+)
 
 type nodeType int
 
@@ -19,17 +23,17 @@ type hashTable struct {
 //TODO: squash this down to use less space. nodeType, result, score, depth, none of these need to be 8 bytes wide
 type hashTableEntry struct {
 	hash     uint64
-	bestMove move
+	bestMove board.Move
 	depth    int
 	score    int
-	result   result
+	result   Result
 	node     nodeType
 }
 
 //size of an entry in bytes
 const HASHTABLEENTRYSIZE int = 8 * 6
 
-//size is in megabytes
+//size is in megabytes. a size of 0 makes a table that doesn't store anything.
 func newHashTable(size int) (ht *hashTable) {
 	ht = new(hashTable)
 	ht.size = uint64(size * 1024 * 1024 / HASHTABLEENTRYSIZE)
@@ -37,18 +41,8 @@ func newHashTable(size int) (ht *hashTable) {
 	return
 }
 
-func initHashTable() {
-	if hashSize == 0 {
-		table = &hashTable{}
-		usingHashtable = false
-	} else {
-		table = newHashTable(hashSize)
-		usingHashtable = true
-	}
-}
-
-func (ht *hashTable) Store(hash uint64, depth int, bestMove move, score int, result result, node nodeType) {
-	if !usingHashtable {
+func (ht *hashTable) Store(hash uint64, depth int, bestMove board.Move, score int, result Result, node nodeType) {
+	if ht.size == 0 {
 		return
 	}
 	ht.Lock()
@@ -75,7 +69,7 @@ func (ht *hashTable) Store(hash uint64, depth int, bestMove move, score int, res
 }
 
 func (ht *hashTable) Load(hash uint64) (entry hashTableEntry, ok bool) {
-	if !usingHashtable {
+	if ht.size == 0 {
 		return
 	}
 	ht.Lock()
@@ -86,3 +80,34 @@ func (ht *hashTable) Load(hash uint64) (entry hashTableEntry, ok bool) {
 	}
 	return
 }
+
+//how full the table is, in permille. only looks at the first thousand entries, the rest should be
+//much the same.
+func (ht *hashTable) hashfull() (used int) {
+	if ht.size == 0 {
+		return 0
+	}
+	ht.Lock()
+	defer ht.Unlock()
+	samples := 1000
+	if ht.size < 1000 {
+		samples = int(ht.size)
+	}
+	for i := 0; i < samples; i++ {
+		if ht.table[i].hash != 0 {
+			used++
+		}
+	}
+	return used * 1000 / samples
+}
+
+//perft results go in the table like search results, with the node count as the score. this makes the
+//table a board.PerftCache, but not one the search can use as well: see Engine.PerftCache.
+func (ht *hashTable) LoadPerft(hash uint64, depth int) (nodes int, ok bool) {
+	entry, ok := ht.Load(hash)
+	return entry.score, ok && entry.depth == depth
+}
+
+func (ht *hashTable) StorePerft(hash uint64, depth, nodes int) {
+	ht.Store(hash, depth, 0, nodes, None, EXACT)
+}
@@ -0,0 +1,209 @@
+package search
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+)
+
+//SearchReporter gets told how a search is going. the interfaces each report it in their own way, and
+//anything embedding the engine can plug in its own.
+type SearchReporter interface {
+	Iteration(p *board.Position, lines []Line, stats SearchStats)           //after each completed depth
+	CurrentMove(depth int, m board.Move, number int, elapsed time.Duration) //as each root move is started
+	Hashfull(permille int)                                                  //after each completed depth
+	Message(s string)                                                       //book moves, mate search results, etc.
+	Finished(p *board.Position, lines []Line, stats SearchStats)            //once at the end of every search
+}
+
+type SearchStats struct {
+	Depth  int //last completed depth
+	Nodes  int
+	Time   time.Duration
+	TBHits int64
+}
+
+func (s SearchStats) NPS() float64 {
+	if s.Time == 0 {
+		return 0
+	}
+	return float64(s.Nodes) / s.Time.Seconds()
+}
+
+//SearchLimits controls a search. zero values mean no limit. with no limits at all the search goes to
+//depth 8.
+type SearchLimits struct {
+	Depth     int
+	MoveTime  int //msec
+	Nodes     int
+	MateIn    int             //only look for a mate in this many moves
+	Infinite  bool            //search until stopped
+	Ponder    bool            //search on the opponent's time. no time limit until PonderHit
+	Time      int             //msec left on the clock of the side to move. used if there's no MoveTime
+	Increment int             //msec
+	MovesToGo int             //moves until the next time control, 0 if there isn't one
+	MultiPV   int             //number of lines to find. defaults to the engine's MultiPV
+	Moves     board.MoveList  //only search these moves at the root
+	Stop      <-chan struct{} //the search stops early when this is closed
+}
+
+//SearchLine is one line of a search result
+type SearchLine struct {
+	Score  int      `json:"score"` //centipawns, from white's point of view
+	Result string   `json:"result,omitempty"`
+	PV     []string `json:"pv"`
+}
+
+//SearchResult is the structured result of a search
+type SearchResult struct {
+	BestMove string       `json:"bestmove"` //0000 if there are no legal moves
+	Ponder   string       `json:"ponder,omitempty"`
+	Lines    []SearchLine `json:"lines"`
+	Depth    int          `json:"depth"`
+	Nodes    int          `json:"nodes"`
+	Time     int64        `json:"time"` //msec
+}
+
+var resultNames = map[Result]string{
+	Checkmate: "checkmate",
+	Stalemate: "stalemate",
+	Tablebase: "tablebase",
+}
+
+func newSearchResult(lines []Line, stats SearchStats) (r SearchResult) {
+	r = SearchResult{BestMove: "0000", Depth: stats.Depth, Nodes: stats.Nodes, Time: stats.Time.Milliseconds()}
+	for _, line := range lines {
+		r.Lines = append(r.Lines, SearchLine{line.Score, resultNames[line.Result], line.Variation.UCImoves()})
+	}
+	if len(lines) > 0 && len(lines[0].Variation) > 0 {
+		r.BestMove = lines[0].Variation[0].UCIstring()
+		if len(lines[0].Variation) > 1 {
+			r.Ponder = lines[0].Variation[1].UCIstring()
+		}
+	}
+	return
+}
+
+//SearchCollector keeps everything reported by a search in memory. for tests, and for programs that want
+//to look at the whole search afterwards.
+type SearchCollector struct {
+	sync.Mutex
+	Iterations   []SearchResult
+	CurrentMoves []string
+	HashUsage    []int //hashfull reports, in permille
+	Messages     []string
+	Result       SearchResult
+	Done         bool
+}
+
+func (c *SearchCollector) Iteration(p *board.Position, lines []Line, stats SearchStats) {
+	c.Lock()
+	c.Iterations = append(c.Iterations, newSearchResult(lines, stats))
+	c.Unlock()
+}
+
+func (c *SearchCollector) CurrentMove(depth int, m board.Move, number int, elapsed time.Duration) {
+	c.Lock()
+	c.CurrentMoves = append(c.CurrentMoves, m.UCIstring())
+	c.Unlock()
+}
+
+func (c *SearchCollector) Hashfull(permille int) {
+	c.Lock()
+	c.HashUsage = append(c.HashUsage, permille)
+	c.Unlock()
+}
+
+func (c *SearchCollector) Message(s string) {
+	c.Lock()
+	c.Messages = append(c.Messages, s)
+	c.Unlock()
+}
+
+func (c *SearchCollector) Finished(p *board.Position, lines []Line, stats SearchStats) {
+	c.Lock()
+	c.Result = newSearchResult(lines, stats)
+	c.Done = true
+	c.Unlock()
+}
+
+//JSONReporter writes each event as a line of JSON with a "type" field: info (one per line of each
+//iteration), currmove, hashfull, message and bestmove.
+type JSONReporter struct {
+	sync.Mutex
+	encoder *json.Encoder
+}
+
+type jsonInfo struct {
+	Type    string   `json:"type"`
+	Depth   int      `json:"depth"`
+	MultiPV int      `json:"multipv"`
+	Score   int      `json:"score"` //centipawns, from white's point of view
+	Result  string   `json:"result,omitempty"`
+	Nodes   int      `json:"nodes"`
+	NPS     int      `json:"nps"`
+	TBHits  int64    `json:"tbhits"`
+	Time    int64    `json:"time"` //msec
+	PV      []string `json:"pv"`
+}
+
+type jsonCurrentMove struct {
+	Type   string `json:"type"`
+	Depth  int    `json:"depth"`
+	Move   string `json:"move"`
+	Number int    `json:"number"`
+}
+
+type jsonHashfull struct {
+	Type     string `json:"type"`
+	Hashfull int    `json:"hashfull"`
+}
+
+type jsonMessage struct {
+	Type    string `json:"type"` //"message" or "error"
+	Message string `json:"message"`
+}
+
+type jsonBestMove struct {
+	Type string `json:"type"`
+	SearchResult
+}
+
+func NewJSONReporter(w io.Writer) *JSONReporter {
+	return &JSONReporter{encoder: json.NewEncoder(w)}
+}
+
+func (j *JSONReporter) send(v interface{}) {
+	j.Lock()
+	j.encoder.Encode(v)
+	j.Unlock()
+}
+
+func (j *JSONReporter) Iteration(p *board.Position, lines []Line, stats SearchStats) {
+	for i, line := range lines {
+		j.send(jsonInfo{"info", stats.Depth, i + 1, line.Score, resultNames[line.Result], stats.Nodes, int(stats.NPS()), stats.TBHits, stats.Time.Milliseconds(), line.Variation.UCImoves()})
+	}
+}
+
+func (j *JSONReporter) CurrentMove(depth int, m board.Move, number int, elapsed time.Duration) {
+	j.send(jsonCurrentMove{"currmove", depth, m.UCIstring(), number})
+}
+
+func (j *JSONReporter) Hashfull(permille int) {
+	j.send(jsonHashfull{"hashfull", permille})
+}
+
+func (j *JSONReporter) Message(s string) {
+	j.send(jsonMessage{"message", s})
+}
+
+func (j *JSONReporter) Error(s string) {
+	j.send(jsonMessage{"error", s})
+}
+
+func (j *JSONReporter) Finished(p *board.Position, lines []Line, stats SearchStats) {
+	j.send(jsonBestMove{"bestmove", newSearchResult(lines, stats)})
+}
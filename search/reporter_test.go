@@ -0,0 +1,51 @@
+package search
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+)
+
+func TestSearchCollector(t *testing.T) {
+	engine := NewEngine()
+	pos := board.NewPosition("6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1")
+	collector := &SearchCollector{}
+	result := engine.Analyse(&pos, SearchLimits{Depth: 3, MultiPV: 2}, collector)
+
+	if !collector.Done || result.BestMove != "a1a8" || collector.Result.BestMove != "a1a8" {
+		t.Errorf("Expected bestmove a1a8, got %s", result.BestMove)
+	}
+	if len(collector.Iterations) != 3 || len(collector.HashUsage) != 3 {
+		t.Errorf("Expected 3 iterations and hashfull reports, got %d and %d", len(collector.Iterations), len(collector.HashUsage))
+	}
+	for i, it := range collector.Iterations {
+		if it.Depth != i+1 || len(it.Lines) != 2 {
+			t.Errorf("Iteration %d: expected depth %d with 2 lines, got depth %d with %d", i, i+1, it.Depth, len(it.Lines))
+		}
+	}
+	moves, _ := board.Movegen(&pos)
+	if len(collector.CurrentMoves) < len(moves) {
+		t.Errorf("Expected every root move to be reported, got %d of %d", len(collector.CurrentMoves), len(moves))
+	}
+}
+
+func TestJSONReporter(t *testing.T) {
+	var out bytes.Buffer
+	pos := board.NewPosition("")
+	NewEngine().Analyse(&pos, SearchLimits{Depth: 2}, NewJSONReporter(&out))
+
+	types := map[string]int{}
+	decoder := json.NewDecoder(&out)
+	for decoder.More() {
+		var event jsonMessage
+		if err := decoder.Decode(&event); err != nil {
+			t.Fatal(err)
+		}
+		types[event.Type]++
+	}
+	if types["info"] != 2 || types["hashfull"] != 2 || types["bestmove"] != 1 || types["currmove"] == 0 {
+		t.Errorf("Unexpected events: %v", types)
+	}
+}
@@ -0,0 +1,542 @@
+package search
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+	"github.com/BenNicholls/Aristocrat2/eval"
+)
+
+//special scores. a mate is scored MATE less the plies it takes, so quicker mates score higher
+const (
+	MATE       int = 1000000
+	MAXMATEPLY int = 1000 //longest mate a score can say. any score this close to MATE is a mate
+)
+
+func isMate(score int) bool {
+	return score > MATE-MAXMATEPLY && score <= MATE || score < -MATE+MAXMATEPLY && score >= -MATE
+}
+
+//moves a mate score a ply further from the mate, for passing it up to the position before. the
+//scores in the hashtable are counted from their own position, so they don't depend on the path there.
+func matePly(score int) int {
+	if !isMate(score) {
+		return score
+	} else if score > 0 {
+		return score - 1
+	}
+	return score + 1
+}
+
+//MateIn turns a score into the number of moves to mate, the way UCI says it: positive if the side
+//the score is for is mating, negative if it's getting mated. ok is false if the score isn't a mate.
+func MateIn(score int) (moves int, ok bool) {
+	if !isMate(score) {
+		return 0, false
+	} else if score > 0 {
+		return (MATE - score + 1) / 2, true
+	}
+	return -((MATE + score + 1) / 2), true
+}
+
+type Result int
+
+const (
+	None Result = iota
+	Stalemate
+	Checkmate
+	Tablebase
+	//insufficient material/tablebase losses/draws/whatever can be added here later
+)
+
+type calculationController struct {
+	sync.RWMutex
+	calculators int  //number of threads currently calculating
+	stop        bool //set to true to stop calculators
+	timeForMove int  //time limit for move (in msec)
+	pondering   bool //searching on the opponent's time. time limit is ignored until ponderhit
+	timer       time.Time
+	nodes       int64           //nodes visited by all calculators in this search. use atomic ops
+	nodeLimit   int64           //stop after this many nodes (0 for no limit)
+	mateIn      int             //if non-zero, we're only looking for a mate in this many moves
+	cancel      <-chan struct{} //stops the search when closed, ie. the Done channel of a request's context
+}
+
+func (cc *calculationController) calculating() bool {
+	cc.RLock()
+	defer cc.RUnlock()
+	if cc.calculators > 0 {
+		return true
+	}
+	return false
+}
+
+//Hi Future Ben, past Ben here. don't put the RUnlock in a defer, it's really slow for some reason.
+func (cc *calculationController) needToStop() bool {
+	cc.RLock()
+	if cc.stop || (cc.timeForMove != 0 && !cc.pondering && int64(cc.timeForMove)-time.Since(cc.timer).Milliseconds() < 10) ||
+		(cc.nodeLimit != 0 && atomic.LoadInt64(&cc.nodes) >= cc.nodeLimit) {
+		cc.RUnlock()
+		return true
+	}
+	select {
+	case <-cc.cancel:
+		cc.RUnlock()
+		return true
+	default:
+	}
+	cc.RUnlock()
+	return false
+}
+
+//stops the calculators, if there are any. a stop with nothing calculating would stop the next search.
+func (cc *calculationController) stopCalculators() {
+	cc.Lock()
+	if cc.calculators > 0 {
+		cc.stop = true
+	}
+	cc.Unlock()
+}
+
+func (cc *calculationController) beginCalculating() {
+	cc.Lock()
+	if cc.calculators == 0 {
+		cc.timer = time.Now()
+		atomic.StoreInt64(&cc.nodes, 0)
+	}
+	cc.calculators++
+	cc.Unlock()
+}
+
+func (cc *calculationController) doneCalculating() {
+	cc.Lock()
+	cc.calculators--
+	if cc.calculators == 0 {
+		cc.stop = false
+		cc.timeForMove = 0
+		cc.pondering = false
+		cc.nodeLimit = 0
+		cc.mateIn = 0
+		cc.cancel = nil
+	}
+	cc.Unlock()
+}
+
+//time since the search started (or since ponderhit, if we were pondering)
+func (cc *calculationController) elapsed() time.Duration {
+	cc.RLock()
+	defer cc.RUnlock()
+	return time.Since(cc.timer)
+}
+
+func (cc *calculationController) mateSearch() int {
+	cc.RLock()
+	defer cc.RUnlock()
+	return cc.mateIn
+}
+
+//sets the time limit for a move based on the clock. remaining and increment are in msec, movesToGo
+//is 0 if the time control doesn't have one.
+func (cc *calculationController) allocateTime(remaining, increment, movesToGo int) {
+	if movesToGo <= 0 {
+		movesToGo = 30
+	}
+	t := remaining/movesToGo + increment*3/4
+	if t > remaining-50 { //leave a bit of a buffer so we don't lose on time
+		t = remaining - 50
+	}
+	if t < 10 {
+		t = 10
+	}
+	cc.Lock()
+	cc.timeForMove = t
+	cc.Unlock()
+}
+
+//sets the limits for the next search. the time limit is worked out from the clock if there isn't one.
+func (cc *calculationController) setLimits(limits SearchLimits) {
+	cc.Lock()
+	cc.timeForMove = limits.MoveTime
+	cc.nodeLimit = int64(limits.Nodes)
+	cc.mateIn = limits.MateIn
+	cc.pondering = limits.Ponder
+	cc.cancel = limits.Stop
+	cc.Unlock()
+	if limits.MoveTime == 0 && limits.Time > 0 {
+		cc.allocateTime(limits.Time, limits.Increment, limits.MovesToGo)
+	}
+}
+
+//the opponent played the move we were pondering on, so the search becomes a normal timed search.
+//the time spent pondering came off the opponent's clock, so the time limit starts counting from now.
+func (cc *calculationController) ponderhit() {
+	cc.Lock()
+	cc.pondering = false
+	cc.timer = time.Now()
+	cc.Unlock()
+}
+
+//blocks until we're no longer pondering. UCI doesn't allow a bestmove while pondering, even if the
+//search finished, so we wait for ponderhit or stop.
+func (cc *calculationController) waitForPonder() {
+	for {
+		cc.RLock()
+		done := cc.stop || !cc.pondering
+		cc.RUnlock()
+		if done {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+//searches p to depth. evals is the searching thread's evaluation stack, which is on p.
+func (e *Engine) search(p *board.Position, evals *eval.Stack, depth, alpha, beta int) (score, nodes int, result Result, continuation board.MoveList) {
+	atomic.AddInt64(&e.controller.nodes, 1)
+	var candidateMove board.Move
+	continuation = make(board.MoveList, 0, 5)
+	if entry, ok := e.table.Load(p.Hash); ok {
+		candidateMove = entry.bestMove
+		if entry.depth >= depth {
+			if entry.node == EXACT {
+				continuation = append(continuation, entry.bestMove)
+				return entry.score, 1, entry.result, continuation
+			} else if entry.node == LOWER && entry.score >= beta { //beta cutoff.
+				//lower bounds are only used for cutoffs. raising alpha with them means the move that
+				//reaches the bound can't beat alpha, so we'd lose the PV (and at the root, the best move)
+				continuation = append(continuation, entry.bestMove)
+				return entry.score, 1, entry.result, continuation
+			}
+		}
+	}
+
+	moves, numCaptures := board.Movegen(p)
+	if len(moves) == 0 {
+		//variant wins and losses count as mates
+		switch p.Outcome() {
+		case board.Lost:
+			return -MATE, 1, Checkmate, continuation
+		case board.Won:
+			return MATE, 1, Checkmate, continuation
+		}
+		return 0, 1, Stalemate, continuation
+	}
+
+	var quiesce bool
+	if depth <= 0 {
+		stand := evals.Eval(p)
+		if numCaptures == 0 { //quiet position. return eval.
+			return stand, 1, None, continuation
+		}
+		if stand > beta {
+			return beta, 1, None, continuation
+		}
+		if stand > alpha {
+			alpha = stand
+		}
+		quiesce = true
+	}
+
+	//find candidate move and place in front
+	if candidateMove != 0 {
+		for i, m := range moves {
+			if m == candidateMove {
+				if i == 0 {
+					break
+				}
+
+				moves[i] = moves[0]
+				moves[0] = candidateMove
+				if moves[i].Capture() && i > numCaptures {
+					//if the replaced move is a capture, make sure it's with the captures at the start of the movelist
+					swap := moves[numCaptures]
+					moves[numCaptures] = moves[i]
+					moves[i] = swap
+				}
+
+				break
+			}
+		}
+	}
+
+	var next board.Position
+	score = -MATE * 2
+	for _, m := range moves {
+		if quiesce && !m.Capture() { //break the search if we are quiescing and we're out of captures to check.
+			if m == candidateMove {
+				continue
+			}
+			break
+		}
+		next = *p
+		next.DoMove(m)
+		v, n, r, c := 0, 1, Tablebase, board.MoveList(nil)
+		if tbScore, ok := e.tablebaseScore(&next); ok {
+			v = tbScore
+		} else {
+			evals.Push(p, &next)
+			v, n, r, c = e.search(&next, evals, depth-1, -beta, -alpha)
+			evals.Pop()
+		}
+		v = matePly(-v)
+		nodes += n
+		if v > score {
+			score = v
+			result = r
+			if v > alpha {
+				alpha = v
+				continuation = continuation[:0]
+				continuation = append(continuation, m)
+				continuation = append(continuation, c...)
+			}
+		}
+
+		if alpha >= beta || (e.controller.needToStop() && !quiesce) {
+			break
+		}
+	}
+
+	if len(continuation) != 0 { //if we found a followup move, add it to the PV and store it
+		if alpha >= beta { //beta cutoff node
+			e.table.Store(p.Hash, depth, continuation[0], score, result, LOWER)
+			score = beta //fail-hard
+		} else {
+			e.table.Store(p.Hash, depth, continuation[0], score, result, EXACT)
+		}
+	} else { //no improving move found.
+		e.table.Store(p.Hash, depth, board.Move(0), score, result, UPPER)
+		score = alpha //fail-hard
+	}
+
+	return
+}
+
+//searches the position with iterative deepening, reporting as it goes to the reporter.
+//returns the lines found (best first) and the final stats. the caller starts the controller
+//calculating, so the search can be stopped as soon as it's launched.
+func (e *Engine) iterativeSearch(p *board.Position, targetDepth int, reporter SearchReporter) (lines []Line, stats SearchStats) {
+	startTime := time.Now()
+	defer func() {
+		//UCI doesn't allow a bestmove while pondering, so even a finished search waits for ponderhit or stop
+		e.controller.waitForPonder()
+		if len(lines) > 0 && len(lines[0].Variation) == 1 {
+			if m, ok := e.ponderMove(p, lines[0].Variation[0]); ok {
+				lines[0].Variation = append(lines[0].Variation, m)
+			}
+		}
+		reporter.Finished(p, lines, stats)
+		e.controller.doneCalculating()
+	}()
+
+	rootMoves, _ := board.Movegen(p)
+	if len(e.searchMoves) > 0 {
+		rootMoves = e.searchMoves
+	}
+
+	if m, ok := e.bookMove(p); ok && rootMoves.Contains(m) {
+		lines = []Line{{0, None, board.MoveList{m}}}
+		reporter.Message("Book move: " + m.String())
+		return
+	}
+
+	atomic.StoreInt64(&e.tbHits, 0)
+	if tbLines, ok := e.tablebaseRootLines(p, rootMoves, e.multiPV); ok {
+		lines = tbLines
+		for i := range lines {
+			lines[i].Score *= board.ScoreModifier[p.ToMove]
+		}
+		stats = SearchStats{Depth: 1, TBHits: atomic.LoadInt64(&e.tbHits)}
+		reporter.Iteration(p, lines, stats)
+		return
+	}
+
+	//start up helper threads. each gets its own copy of the position, since the move history is a slice
+	var helpers sync.WaitGroup
+	for i := 1; i < e.Threads; i++ {
+		e.controller.beginCalculating()
+		helpers.Add(1)
+		go func(p board.Position, id int) {
+			e.helperSearch(&p, e.evaluator.NewStack(&p), targetDepth, id)
+			helpers.Done()
+		}(p.Copy(), i)
+	}
+
+	if targetDepth < 1 {
+		targetDepth = 1
+	}
+	mateIn := e.controller.mateSearch()
+	if mateIn != 0 && targetDepth > mateIn*2-1 {
+		targetDepth = mateIn*2 - 1
+	}
+	mateFound, stopped := false, false
+	evals := e.evaluator.NewStack(p)
+
+	for depth := 1; depth <= targetDepth; depth++ {
+		var nodes int
+		lines, nodes = e.searchMultiPV(p, evals, depth, rootMoves, lines, reporter)
+		stats.Depth = depth
+		stats.Nodes += nodes
+		stats.Time = time.Since(startTime)
+		stats.TBHits = atomic.LoadInt64(&e.tbHits)
+		reporter.Iteration(p, lines, stats)
+		reporter.Hashfull(e.table.hashfull())
+
+		if n, ok := MateIn(lines[0].Score * board.ScoreModifier[p.ToMove]); mateIn != 0 && ok && n > 0 && n <= mateIn && lines[0].Result == Checkmate {
+			mateFound = true
+			reporter.Message(fmt.Sprintf("mate in %d found", n))
+			break
+		}
+		if e.controller.needToStop() {
+			stopped = true
+			break
+		}
+	}
+	if mateIn != 0 && !mateFound && !stopped {
+		reporter.Message(fmt.Sprintf("no mate in %d", mateIn))
+	}
+
+	e.controller.waitForPonder() //before stopping the helpers, or the stop would end the ponder too
+	if e.Threads > 1 {
+		e.controller.stopCalculators()
+		helpers.Wait()
+	}
+
+	stats.Time = time.Since(startTime)
+	return
+}
+
+//finds the reply we expect to a move from the hashtable, so there's something to ponder on when the
+//best line is only one move long.
+func (e *Engine) ponderMove(p *board.Position, m board.Move) (reply board.Move, ok bool) {
+	next := *p
+	next.DoMove(m)
+	entry, ok := e.table.Load(next.Hash)
+	if !ok || entry.bestMove == 0 {
+		return 0, false
+	}
+	moves, _ := board.Movegen(&next)
+	return entry.bestMove, moves.Contains(entry.bestMove)
+}
+
+//one of the lines found by the search. score is from white's point of view.
+type Line struct {
+	Score     int
+	Result    Result
+	Variation board.MoveList
+}
+
+//finds the best multiPV lines by searching the root moves multiPV times, taking out the first move
+//of each line found. the previous iteration's lines are searched first.
+func (e *Engine) searchMultiPV(p *board.Position, evals *eval.Stack, depth int, moves board.MoveList, previous []Line, reporter SearchReporter) (lines []Line, nodes int) {
+	rootMoves := make(board.MoveList, len(moves))
+	copy(rootMoves, moves)
+	if len(rootMoves) == 0 {
+		score, n, result, _ := e.search(p, evals, depth, -MATE*2, MATE*2)
+		return []Line{{score * board.ScoreModifier[p.ToMove], result, nil}}, n
+	}
+
+	//order root moves by the previous iteration's lines
+	for i := len(previous) - 1; i >= 0; i-- {
+		if len(previous[i].Variation) == 0 {
+			continue
+		}
+		for j, m := range rootMoves {
+			if m == previous[i].Variation[0] {
+				copy(rootMoves[1:j+1], rootMoves[:j])
+				rootMoves[0] = m
+				break
+			}
+		}
+	}
+
+	for len(lines) < e.multiPV && len(rootMoves) > 0 {
+		score, n, result, variation := e.searchRoot(p, evals, depth, rootMoves, reporter)
+		nodes += n
+		if len(variation) == 0 {
+			break
+		}
+		lines = append(lines, Line{score * board.ScoreModifier[p.ToMove], result, variation})
+
+		for i, m := range rootMoves {
+			if m == variation[0] {
+				rootMoves = append(rootMoves[:i], rootMoves[i+1:]...)
+				break
+			}
+		}
+
+		if e.controller.needToStop() {
+			break
+		}
+	}
+
+	//if we were stopped partway through, fill in the rest from the last iteration
+	for _, line := range previous {
+		if len(lines) >= e.multiPV {
+			break
+		}
+		if len(line.Variation) > 0 && !linesInclude(lines, line.Variation[0]) {
+			lines = append(lines, line)
+		}
+	}
+
+	return
+}
+
+//reports whether any of the lines start with move m
+func linesInclude(lines []Line, m board.Move) bool {
+	for _, line := range lines {
+		if len(line.Variation) > 0 && line.Variation[0] == m {
+			return true
+		}
+	}
+	return false
+}
+
+//searches the root position, only considering the given moves. the root position doesn't go in the
+//hashtable since its result depends on which moves we're looking at.
+func (e *Engine) searchRoot(p *board.Position, evals *eval.Stack, depth int, moves board.MoveList, reporter SearchReporter) (score, nodes int, result Result, continuation board.MoveList) {
+	alpha, beta := -MATE*2, MATE*2
+	score = -MATE * 2
+	for i, m := range moves {
+		reporter.CurrentMove(depth, m, i+1, e.controller.elapsed())
+		next := *p
+		next.DoMove(m)
+		v, n, r, c := 0, 1, Tablebase, board.MoveList(nil)
+		if tbScore, ok := e.tablebaseScore(&next); ok {
+			v = tbScore
+		} else {
+			evals.Push(p, &next)
+			v, n, r, c = e.search(&next, evals, depth-1, -beta, -alpha)
+			evals.Pop()
+		}
+		v = matePly(-v)
+		nodes += n
+		if v > score {
+			score = v
+			result = r
+			continuation = append(board.MoveList{m}, c...)
+			if v > alpha {
+				alpha = v
+			}
+		}
+
+		if e.controller.needToStop() {
+			break
+		}
+	}
+
+	return
+}
+
+//helper threads for lazy SMP. they search the same position as the main thread, filling the shared
+//hashtable as they go. odd helpers start a ply deeper so the threads don't all search in lockstep.
+//results are thrown away, the main thread picks them up from the table.
+func (e *Engine) helperSearch(p *board.Position, evals *eval.Stack, targetDepth, id int) {
+	for depth := 1 + id%2; depth <= targetDepth && !e.controller.needToStop(); depth++ {
+		e.search(p, evals, depth, -MATE*2, MATE*2)
+	}
+	e.controller.doneCalculating()
+}
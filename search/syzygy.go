@@ -0,0 +1,1190 @@
+package search
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+)
+
+//Syzygy endgame tablebase probing. This follows the probing code from Stockfish (which itself is
+//based on Ronald de Man's original), since that's the closest thing the format has to a spec.
+//
+//The tables use their own square numbering (a1 = 0, h8 = 63) and piece codes (white pieces 1-6,
+//black pieces 9-14, in the order pawn..king), so everything gets converted on the way in.
+//Files are read into memory in full the first time they're probed.
+
+const TBPIECES int = 7
+
+//score for a tablebase win. well above any eval, well below MATE
+const TBWIN int = 100000
+
+//file magic numbers
+var tbWDLMagic = []byte{0x71, 0xE8, 0x23, 0x5D}
+var tbDTZMagic = []byte{0xD7, 0x66, 0x0C, 0xA5}
+
+//WDL results, from the point of view of the side to move. cursed wins and blessed losses are
+//wins/losses that the 50 move rule turns into draws.
+const (
+	WDL_LOSS        int = -2
+	WDL_BLESSEDLOSS int = -1
+	WDL_DRAW        int = 0
+	WDL_CURSEDWIN   int = 1
+	WDL_WIN         int = 2
+)
+
+//table flags
+const (
+	TB_STM         = 1
+	TB_MAPPED      = 2
+	TB_WINPLIES    = 4
+	TB_LOSSPLIES   = 8
+	TB_WIDE        = 16
+	TB_SINGLEVALUE = 128
+)
+
+type tbState int
+
+const (
+	TB_FAIL            tbState = iota //probe failed (missing or broken table)
+	TB_OK                             //probe succeeded
+	TB_CHANGESTM                      //DTZ table only has the other side to move
+	TB_ZEROINGBESTMOVE                //best move zeroes the 50 move counter
+)
+
+//encoding tables, indexed by tablebase squares
+var tbMapPawns [64]int
+var tbMapB1H1H7 [64]int
+var tbMapA1D1D4 [64]int
+var tbMapKK [10][64]int
+var tbBinomial [TBPIECES][64]uint64
+var tbLeadPawnIdx [TBPIECES][64]uint64
+var tbLeadPawnsSize [TBPIECES][4]uint64
+
+type tbRegistry struct {
+	wdl     map[uint64]*tbTable
+	dtz     map[uint64]*tbTable
+	largest int //most pieces in any of the tables we have
+}
+
+//the compression data for one side/file of a table
+type pairsData struct {
+	buf             []byte //the whole table file
+	flags           int
+	sizeofBlock     uint64
+	span            uint64
+	numBlocks       int
+	maxSymLen       int
+	minSymLen       int
+	lowestSym       int //offset of the lowest symbol of each length
+	btree           int //offset of the symbol tree
+	blockLength     int //offset of the block lengths
+	blockLengthSize int
+	sparseIndex     int //offset of the sparse index
+	sparseIndexSize int
+	data            int //offset of the compressed data
+	base64          []uint64
+	symlen          []int
+	pieces          [TBPIECES]int
+	groupIdx        [TBPIECES + 1]uint64
+	groupLen        [TBPIECES + 1]int
+	mapIdx          [4]int //where to find the DTZ value maps for each WDL result
+}
+
+type tbTable struct {
+	filename        string
+	dtz             bool
+	key, key2       uint64 //material keys with the stronger side as white, and as black
+	pieceCount      int
+	hasPawns        bool
+	hasUniquePieces bool
+	pawnCount       [2]int //lead colour, other colour
+
+	loader sync.Once
+	ready  bool
+	data   []byte
+	items  [2][4]pairsData //indexed by side to move and file
+	dtzMap int             //offset of the DTZ value maps
+}
+
+func init() {
+	//tbMapB1H1H7 encodes a square below the a1-h8 diagonal to 0..27
+	code := 0
+	for s := 0; s < 64; s++ {
+		if tbOffDiagonal(s) < 0 {
+			tbMapB1H1H7[s] = code
+			code++
+		}
+	}
+
+	//tbMapA1D1D4 encodes a square in the a1-d1-d4 triangle to 0..9, diagonal squares last
+	diagonal := make([]int, 0, 4)
+	code = 0
+	for s := 0; s <= 27; s++ {
+		if tbOffDiagonal(s) < 0 && s&7 <= 3 {
+			tbMapA1D1D4[s] = code
+			code++
+		} else if tbOffDiagonal(s) == 0 && s&7 <= 3 {
+			diagonal = append(diagonal, s)
+		}
+	}
+	for _, s := range diagonal {
+		tbMapA1D1D4[s] = code
+		code++
+	}
+
+	//tbMapKK encodes the 462 legal ways to place two kings with the first in the a1-d1-d4
+	//triangle. if the first king is on the diagonal, the second can't be above it.
+	type kingPair struct{ idx, sq int }
+	bothOnDiagonal := make([]kingPair, 0)
+	code = 0
+	for idx := 0; idx < 10; idx++ {
+		for s1 := 0; s1 <= 27; s1++ {
+			if tbMapA1D1D4[s1] != idx || (idx == 0 && s1 != 1) { //b1 is mapped to 0
+				continue
+			}
+			for s2 := 0; s2 < 64; s2++ {
+				if tbDistance(s1, s2) <= 1 {
+					continue //kings touching
+				} else if tbOffDiagonal(s1) == 0 && tbOffDiagonal(s2) > 0 {
+					continue //first on the diagonal, second above
+				} else if tbOffDiagonal(s1) == 0 && tbOffDiagonal(s2) == 0 {
+					bothOnDiagonal = append(bothOnDiagonal, kingPair{idx, s2})
+				} else {
+					tbMapKK[idx][s2] = code
+					code++
+				}
+			}
+		}
+	}
+	for _, kp := range bothOnDiagonal {
+		tbMapKK[kp.idx][kp.sq] = code
+		code++
+	}
+
+	//binomial coefficients: tbBinomial[k][n] ways to choose k things from n
+	tbBinomial[0][0] = 1
+	for n := 1; n < 64; n++ {
+		for k := 0; k < TBPIECES && k <= n; k++ {
+			if k > 0 {
+				tbBinomial[k][n] += tbBinomial[k-1][n-1]
+			}
+			if k < n {
+				tbBinomial[k][n] += tbBinomial[k][n-1]
+			}
+		}
+	}
+
+	//tbMapPawns encodes a2-h7 to 0..47, the leading pawn being the one with the highest value (nearest
+	//the edge, lowest rank). also build the index tables for the leading pawn group.
+	availableSquares := 47
+	for leadPawnsCnt := 1; leadPawnsCnt < TBPIECES; leadPawnsCnt++ {
+		for f := 0; f < 4; f++ {
+			idx := uint64(0)
+			for r := 1; r <= 6; r++ {
+				sq := r*8 + f
+				if leadPawnsCnt == 1 {
+					tbMapPawns[sq] = availableSquares
+					availableSquares--
+					tbMapPawns[sq^7] = availableSquares
+					availableSquares--
+				}
+				tbLeadPawnIdx[leadPawnsCnt][sq] = idx
+				idx += tbBinomial[leadPawnsCnt-1][tbMapPawns[sq]]
+			}
+			tbLeadPawnsSize[leadPawnsCnt][f] = idx
+		}
+	}
+}
+
+//rank - file for a tablebase square: 0 on the a1-h8 diagonal, negative below it
+func tbOffDiagonal(s int) int {
+	return s>>3 - s&7
+}
+
+func tbDistance(s1, s2 int) int {
+	rd, fd := s1>>3-s2>>3, s1&7-s2&7
+	if rd < 0 {
+		rd = -rd
+	}
+	if fd < 0 {
+		fd = -fd
+	}
+	if rd > fd {
+		return rd
+	}
+	return fd
+}
+
+//material key built from the piece counts of each colour.
+func materialKey(counts [2][6]int) (key uint64) {
+	for colour := board.WHITE; colour <= board.BLACK; colour++ {
+		for piece := board.PAWN; piece <= board.KING; piece++ {
+			key |= uint64(counts[colour][piece]) << (4 * (colour*6 + piece))
+		}
+	}
+	return
+}
+
+func positionMaterialKey(p *board.Position) uint64 {
+	var counts [2][6]int
+	for colour := board.WHITE; colour <= board.BLACK; colour++ {
+		for piece := board.PAWN; piece <= board.KING; piece++ {
+			counts[colour][piece] = board.CountBits(p.Colours[colour] & p.Pieces[piece])
+		}
+	}
+	return materialKey(counts)
+}
+
+//loads the tablebases found in path, which can be a list of directories. replaces any loaded tables.
+func (tb *tbRegistry) init(path string) (count int) {
+	*tb = tbRegistry{
+		wdl: make(map[uint64]*tbTable),
+		dtz: make(map[uint64]*tbTable),
+	}
+
+	for _, dir := range filepath.SplitList(path) {
+		files, err := filepath.Glob(filepath.Join(dir, "*.rtbw"))
+		if err != nil {
+			continue
+		}
+		for _, filename := range files {
+			name := strings.TrimSuffix(filepath.Base(filename), ".rtbw")
+			wdl, ok := newTBTable(name)
+			if !ok {
+				continue
+			}
+			if _, exists := tb.wdl[wdl.key]; exists {
+				continue
+			}
+			wdl.filename = filename
+			tb.wdl[wdl.key] = wdl
+			tb.wdl[wdl.key2] = wdl
+			if wdl.pieceCount > tb.largest {
+				tb.largest = wdl.pieceCount
+			}
+			count++
+
+			dtzFile := strings.TrimSuffix(filename, ".rtbw") + ".rtbz"
+			if _, err := os.Stat(dtzFile); err == nil {
+				dtz, _ := newTBTable(name)
+				dtz.dtz = true
+				dtz.filename = dtzFile
+				tb.dtz[dtz.key] = dtz
+				tb.dtz[dtz.key2] = dtz
+			}
+		}
+	}
+
+	return
+}
+
+//sets up a table from its name, eg. KRvKP. the side before the v is white.
+func newTBTable(name string) (t *tbTable, ok bool) {
+	sides := strings.Split(name, "v")
+	if len(sides) != 2 {
+		return
+	}
+
+	var counts [2][6]int
+	pieceCount := 0
+	for colour, side := range sides {
+		if !strings.HasPrefix(side, "K") || strings.Count(side, "K") != 1 {
+			return
+		}
+		for _, ch := range side {
+			pieceColour, piece, known := board.ParsePiece(ch)
+			if !known || pieceColour != board.WHITE {
+				return
+			}
+			counts[colour][piece]++
+			pieceCount++
+		}
+	}
+	if pieceCount > TBPIECES {
+		return
+	}
+
+	t = &tbTable{pieceCount: pieceCount}
+	t.key = materialKey(counts)
+	t.key2 = materialKey([2][6]int{counts[board.BLACK], counts[board.WHITE]})
+	t.hasPawns = counts[board.WHITE][board.PAWN]+counts[board.BLACK][board.PAWN] > 0
+	for colour := board.WHITE; colour <= board.BLACK; colour++ {
+		for piece := board.PAWN; piece < board.KING; piece++ {
+			if counts[colour][piece] == 1 {
+				t.hasUniquePieces = true
+			}
+		}
+	}
+
+	//the leading colour is the side with fewer pawns (if both have some) since it compresses better
+	lead := board.WHITE
+	if counts[board.BLACK][board.PAWN] != 0 && (counts[board.WHITE][board.PAWN] == 0 || counts[board.BLACK][board.PAWN] < counts[board.WHITE][board.PAWN]) {
+		lead = board.BLACK
+	}
+	t.pawnCount[0] = counts[lead][board.PAWN]
+	t.pawnCount[1] = counts[board.Opponent(lead)][board.PAWN]
+
+	return t, true
+}
+
+func (t *tbTable) get(stm, f int) *pairsData {
+	if t.dtz {
+		stm = 0
+	}
+	if !t.hasPawns {
+		f = 0
+	}
+	return &t.items[stm][f]
+}
+
+//reads the table file the first time it is needed. reports whether the table is usable.
+func (t *tbTable) load() bool {
+	t.loader.Do(func() {
+		data, err := os.ReadFile(t.filename)
+		if err != nil {
+			return
+		}
+		magic := tbWDLMagic
+		if t.dtz {
+			magic = tbDTZMagic
+		}
+		if len(data) < 5 || string(data[:4]) != string(magic) {
+			return
+		}
+		t.data = data
+		t.ready = t.setup()
+	})
+	return t.ready
+}
+
+//parses the table header and locates all of the compression data.
+func (t *tbTable) setup() (ok bool) {
+	//corrupted files can send offsets anywhere. don't let them take the engine down with them
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+
+	data := t.data
+	pos := 4
+	if (data[pos]&2 != 0) != t.hasPawns || (data[pos]&1 != 0) != (t.key != t.key2) {
+		return false
+	}
+	pos++
+
+	sides := 1
+	if !t.dtz && t.key != t.key2 {
+		sides = 2
+	}
+	maxFile := 0
+	if t.hasPawns {
+		maxFile = 3
+	}
+	pp := t.hasPawns && t.pawnCount[1] > 0 //pawns on both sides
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			*t.get(i, f) = pairsData{buf: data}
+		}
+
+		order := [2][2]int{{int(data[pos] & 0xF), 0xF}, {int(data[pos] >> 4), 0xF}}
+		if pp {
+			order[0][1] = int(data[pos+1] & 0xF)
+			order[1][1] = int(data[pos+1] >> 4)
+			pos++
+		}
+		pos++
+
+		for k := 0; k < t.pieceCount; k, pos = k+1, pos+1 {
+			for i := 0; i < sides; i++ {
+				if i == 0 {
+					t.get(i, f).pieces[k] = int(data[pos] & 0xF)
+				} else {
+					t.get(i, f).pieces[k] = int(data[pos] >> 4)
+				}
+			}
+		}
+
+		for i := 0; i < sides; i++ {
+			t.setGroups(t.get(i, f), order[i], f)
+		}
+	}
+	pos += pos & 1 //word alignment
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			pos = t.get(i, f).setSizes(pos)
+		}
+	}
+
+	if t.dtz {
+		pos = t.setDTZMap(pos, maxFile)
+	}
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			d := t.get(i, f)
+			d.sparseIndex = pos
+			pos += d.sparseIndexSize * 6
+		}
+	}
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			d := t.get(i, f)
+			d.blockLength = pos
+			pos += d.blockLengthSize * 2
+		}
+	}
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			pos = (pos + 0x3F) &^ 0x3F //64 byte alignment
+			d := t.get(i, f)
+			d.data = pos
+			pos += d.numBlocks * int(d.sizeofBlock)
+		}
+	}
+
+	return pos <= len(data)
+}
+
+//works out how the pieces are grouped together for encoding, and the index multiplier for each group.
+func (t *tbTable) setGroups(d *pairsData, order [2]int, f int) {
+	n := 0
+	firstLen := 2
+	if t.hasPawns {
+		firstLen = 0
+	} else if t.hasUniquePieces {
+		firstLen = 3
+	}
+
+	//the first group is the leading pieces/pawns, then pieces of the same kind are grouped together
+	d.groupLen[n] = 1
+	for i := 1; i < t.pieceCount; i++ {
+		firstLen--
+		if firstLen > 0 || d.pieces[i] == d.pieces[i-1] {
+			d.groupLen[n]++
+		} else {
+			n++
+			d.groupLen[n] = 1
+		}
+	}
+	n++
+	d.groupLen[n] = 0 //zero terminated
+
+	//groups are encoded in the order given by the table, leading group at order[0] and remaining
+	//pawns (if any) at order[1]
+	pp := t.hasPawns && t.pawnCount[1] > 0
+	next := 1
+	freeSquares := 64 - d.groupLen[0]
+	if pp {
+		next = 2
+		freeSquares -= d.groupLen[1]
+	}
+	idx := uint64(1)
+
+	for k := 0; next < n || k == order[0] || k == order[1]; k++ {
+		if k == order[0] { //leading pawns or pieces
+			d.groupIdx[0] = idx
+			if t.hasPawns {
+				idx *= tbLeadPawnsSize[d.groupLen[0]][f]
+			} else if t.hasUniquePieces {
+				idx *= 31332
+			} else {
+				idx *= 462
+			}
+		} else if k == order[1] { //remaining pawns
+			d.groupIdx[1] = idx
+			idx *= tbBinomial[d.groupLen[1]][48-d.groupLen[0]]
+		} else { //remaining pieces
+			d.groupIdx[next] = idx
+			idx *= tbBinomial[d.groupLen[next]][freeSquares]
+			freeSquares -= d.groupLen[next]
+			next++
+		}
+	}
+
+	d.groupIdx[n] = idx
+}
+
+//reads the sizes of the compression data and sets up the huffman decoding tables.
+func (d *pairsData) setSizes(pos int) int {
+	data := d.buf
+	d.flags = int(data[pos])
+	pos++
+	if d.flags&TB_SINGLEVALUE != 0 {
+		d.minSymLen = int(data[pos]) //the value every position in the table has
+		return pos + 1
+	}
+
+	//the groupIdx at the zero terminator of groupLen is the size of the table
+	n := 0
+	for d.groupLen[n] != 0 {
+		n++
+	}
+	tbSize := d.groupIdx[n]
+
+	d.sizeofBlock = 1 << data[pos]
+	d.span = 1 << data[pos+1]
+	d.sparseIndexSize = int((tbSize + d.span - 1) / d.span)
+	padding := int(data[pos+2])
+	d.numBlocks = int(binary.LittleEndian.Uint32(data[pos+3:]))
+	d.blockLengthSize = d.numBlocks + padding //padded so the sparse index can't point out of range
+	d.maxSymLen = int(data[pos+7])
+	d.minSymLen = int(data[pos+8])
+	pos += 9
+	d.lowestSym = pos
+
+	//symbols are canonical huffman codes, longer codes have lower values. base64[l] is the lowest
+	//symbol of length l + minSymLen, padded out to 64 bits.
+	d.base64 = make([]uint64, d.maxSymLen-d.minSymLen+1)
+	for i := len(d.base64) - 2; i >= 0; i-- {
+		d.base64[i] = (d.base64[i+1] + uint64(d.lowest(i)) - uint64(d.lowest(i+1))) / 2
+	}
+	for i := range d.base64 {
+		d.base64[i] <<= uint(64 - i - d.minSymLen)
+	}
+	pos += 2 * len(d.base64)
+
+	d.symlen = make([]int, binary.LittleEndian.Uint16(data[pos:]))
+	pos += 2
+	d.btree = pos
+
+	//symbols expand recursively into pairs of symbols. symlen is the number of values (minus one)
+	//each symbol expands to.
+	visited := make([]bool, len(d.symlen))
+	for sym := range d.symlen {
+		if !visited[sym] {
+			d.symlen[sym] = d.setSymlen(sym, visited)
+		}
+	}
+
+	return pos + 3*len(d.symlen) + len(d.symlen)&1
+}
+
+func (d *pairsData) setSymlen(sym int, visited []bool) int {
+	visited[sym] = true
+	right := d.right(sym)
+	if right == 0xFFF {
+		return 0
+	}
+	left := d.left(sym)
+	if !visited[left] {
+		d.symlen[left] = d.setSymlen(left, visited)
+	}
+	if !visited[right] {
+		d.symlen[right] = d.setSymlen(right, visited)
+	}
+	return d.symlen[left] + d.symlen[right] + 1
+}
+
+//lowest symbol of length l + minSymLen
+func (d *pairsData) lowest(l int) int {
+	return int(binary.LittleEndian.Uint16(d.buf[d.lowestSym+2*l:]))
+}
+
+//the symbol tree is stored as 12 bit pairs, 3 bytes per symbol
+func (d *pairsData) left(sym int) int {
+	lr := d.buf[d.btree+3*sym:]
+	return int(lr[1]&0xF)<<8 | int(lr[0])
+}
+
+func (d *pairsData) right(sym int) int {
+	lr := d.buf[d.btree+3*sym:]
+	return int(lr[2])<<4 | int(lr[1]>>4)
+}
+
+func (t *tbTable) setDTZMap(pos, maxFile int) int {
+	t.dtzMap = pos
+	for f := 0; f <= maxFile; f++ {
+		d := t.get(0, f)
+		if d.flags&TB_MAPPED == 0 {
+			continue
+		}
+		if d.flags&TB_WIDE != 0 {
+			pos += pos & 1 //word alignment
+			for i := 0; i < 4; i++ {
+				d.mapIdx[i] = (pos-t.dtzMap)/2 + 1
+				pos += 2*int(binary.LittleEndian.Uint16(t.data[pos:])) + 2
+			}
+		} else {
+			for i := 0; i < 4; i++ {
+				d.mapIdx[i] = pos - t.dtzMap + 1
+				pos += int(t.data[pos]) + 1
+			}
+		}
+	}
+	return pos + pos&1
+}
+
+//finds the value stored at index idx
+func (d *pairsData) decompress(idx uint64) int {
+	if d.flags&TB_SINGLEVALUE != 0 {
+		return d.minSymLen
+	}
+
+	//the sparse index points to known positions in the block lengths, one every span values.
+	//find the nearest one and step through the blocks from there.
+	k := idx / d.span
+	entry := d.buf[d.sparseIndex+6*int(k):]
+	block := int(binary.LittleEndian.Uint32(entry))
+	offset := int(binary.LittleEndian.Uint16(entry[4:]))
+	offset += int(idx%d.span) - int(d.span/2)
+
+	for offset < 0 {
+		block--
+		offset += d.blockLen(block) + 1
+	}
+	for offset > d.blockLen(block) {
+		offset -= d.blockLen(block) + 1
+		block++
+	}
+
+	//now decode huffman symbols from the start of the block until we reach our offset
+	ptr := d.data + block*int(d.sizeofBlock)
+	buf64 := binary.BigEndian.Uint64(d.buf[ptr:])
+	ptr += 8
+	buf64Size := 64
+	var sym int
+
+	for {
+		l := 0
+		for buf64 < d.base64[l] {
+			l++
+		}
+		sym = int((buf64 - d.base64[l]) >> uint(64-l-d.minSymLen))
+		sym += d.lowest(l)
+
+		if offset < d.symlen[sym]+1 {
+			break
+		}
+
+		offset -= d.symlen[sym] + 1
+		l += d.minSymLen
+		buf64 <<= uint(l)
+		buf64Size -= l
+
+		if buf64Size <= 32 { //refill
+			buf64Size += 32
+			buf64 |= uint64(binary.BigEndian.Uint32(d.buf[ptr:])) << uint(64-buf64Size)
+			ptr += 4
+		}
+	}
+
+	//the symbol expands to several values. walk down the tree to the one we want.
+	for d.symlen[sym] != 0 {
+		left := d.left(sym)
+		if offset < d.symlen[left]+1 {
+			sym = left
+		} else {
+			offset -= d.symlen[left] + 1
+			sym = d.right(sym)
+		}
+	}
+
+	return d.left(sym)
+}
+
+func (d *pairsData) blockLen(block int) int {
+	return int(binary.LittleEndian.Uint16(d.buf[d.blockLength+2*block:]))
+}
+
+//converts a value from the table into a WDL result or DTZ in plies.
+func (t *tbTable) mapScore(f, value, wdl int) int {
+	if !t.dtz {
+		return value - 2
+	}
+
+	wdlMap := [5]int{1, 3, 0, 2, 0}
+	d := t.get(0, f)
+	if d.flags&TB_MAPPED != 0 {
+		idx := d.mapIdx[wdlMap[wdl+2]] + value
+		if d.flags&TB_WIDE != 0 {
+			value = int(binary.LittleEndian.Uint16(t.data[t.dtzMap+2*idx:]))
+		} else {
+			value = int(t.data[t.dtzMap+idx])
+		}
+	}
+
+	//tables store either moves or plies. we want plies.
+	if (wdl == WDL_WIN && d.flags&TB_WINPLIES == 0) || (wdl == WDL_LOSS && d.flags&TB_LOSSPLIES == 0) || wdl == WDL_CURSEDWIN || wdl == WDL_BLESSEDLOSS {
+		value *= 2
+	}
+
+	return value + 1
+}
+
+//tablebase piece code for the piece on one of our squares
+func tbPieceCode(p *board.Position, square int) int {
+	code := p.GetPieceOnSquare(square) + 1
+	if board.CheckBit(p.Colours[board.BLACK], square) {
+		code += 8
+	}
+	return code
+}
+
+//looks up the position in the table. the table must match the position's material.
+func (t *tbTable) probe(p *board.Position, wdl int) (value int, state tbState) {
+	var squares [TBPIECES]int
+	var pieces [TBPIECES]int
+	var leadPawns uint64
+	size, leadPawnsCnt, tbFile := 0, 0, 0
+
+	//tables are stored with the stronger side as white. if black is the stronger side here we flip
+	//colours and squares. symmetric tables only store white to move, so flip those too if needed.
+	symmetricBlackToMove := t.key == t.key2 && p.ToMove == board.BLACK
+	blackStronger := positionMaterialKey(p) != t.key
+	flipColour, flipSquares, stm := 0, 0, p.ToMove
+	if symmetricBlackToMove || blackStronger {
+		flipColour, flipSquares, stm = 8, 56, board.Opponent(p.ToMove)
+	}
+
+	//our squares are a8 = 0, tablebase squares are a1 = 0
+	tbSquare := func(square int) int {
+		return square ^ 56 ^ flipSquares
+	}
+
+	//tables with pawns are split into 4 by the file of the leading pawn
+	if t.hasPawns {
+		leadColour := (t.get(0, 0).pieces[0] ^ flipColour) >> 3
+		leadPawns = p.Pieces[board.PAWN] & p.Colours[leadColour]
+		board.ForEachBit(leadPawns, func(square int) {
+			squares[size] = tbSquare(square)
+			size++
+		})
+		leadPawnsCnt = size
+
+		lead := 0
+		for i := 1; i < leadPawnsCnt; i++ {
+			if tbMapPawns[squares[i]] > tbMapPawns[squares[lead]] {
+				lead = i
+			}
+		}
+		squares[0], squares[lead] = squares[lead], squares[0]
+
+		tbFile = squares[0] & 7
+		if tbFile > 3 {
+			tbFile = (squares[0] ^ 7) & 7
+		}
+	}
+
+	//DTZ tables are one-sided. if the table has the wrong side to move, the caller has to search
+	if t.dtz {
+		flags := t.get(stm, tbFile).flags
+		if flags&TB_STM != stm && !(t.key == t.key2 && !t.hasPawns) {
+			return 0, TB_CHANGESTM
+		}
+	}
+
+	board.ForEachBit((p.Colours[board.WHITE]|p.Colours[board.BLACK])&^leadPawns, func(square int) {
+		squares[size] = tbSquare(square)
+		pieces[size] = tbPieceCode(p, square) ^ flipColour
+		size++
+	})
+
+	d := t.get(stm, tbFile)
+
+	//reorder the pieces to match the order the table was encoded in
+	for i := leadPawnsCnt; i < size-1; i++ {
+		for j := i + 1; j < size; j++ {
+			if d.pieces[i] == pieces[j] {
+				pieces[i], pieces[j] = pieces[j], pieces[i]
+				squares[i], squares[j] = squares[j], squares[i]
+				break
+			}
+		}
+	}
+
+	//mirror so the leading piece is on files a-d
+	if squares[0]&7 > 3 {
+		for i := 0; i < size; i++ {
+			squares[i] ^= 7
+		}
+	}
+
+	var idx uint64
+	if t.hasPawns {
+		idx = tbLeadPawnIdx[leadPawnsCnt][squares[0]]
+		sort.SliceStable(squares[1:leadPawnsCnt], func(i, j int) bool {
+			return tbMapPawns[squares[1+i]] < tbMapPawns[squares[1+j]]
+		})
+		for i := 1; i < leadPawnsCnt; i++ {
+			idx += tbBinomial[i][tbMapPawns[squares[i]]]
+		}
+	} else {
+		//without pawns we can also mirror so the leading piece is below rank 5...
+		if squares[0]>>3 > 3 {
+			for i := 0; i < size; i++ {
+				squares[i] ^= 56
+			}
+		}
+
+		//...and flip along the a1-h8 diagonal so the first leading piece not on it is below it
+		for i := 0; i < d.groupLen[0]; i++ {
+			if tbOffDiagonal(squares[i]) == 0 {
+				continue
+			}
+			if tbOffDiagonal(squares[i]) > 0 {
+				for j := i; j < size; j++ {
+					squares[j] = ((squares[j] >> 3) | (squares[j] << 3)) & 63
+				}
+			}
+			break
+		}
+
+		//encode the leading group. with 3 unique pieces (kings included) they are encoded together,
+		//otherwise just the kings
+		if t.hasUniquePieces {
+			adjust1, adjust2 := 0, 0
+			if squares[1] > squares[0] {
+				adjust1++
+			}
+			if squares[2] > squares[0] {
+				adjust2++
+			}
+			if squares[2] > squares[1] {
+				adjust2++
+			}
+
+			if tbOffDiagonal(squares[0]) != 0 {
+				idx = uint64((tbMapA1D1D4[squares[0]]*63+squares[1]-adjust1)*62 + squares[2] - adjust2)
+			} else if tbOffDiagonal(squares[1]) != 0 {
+				idx = uint64((6*63+(squares[0]>>3)*28+tbMapB1H1H7[squares[1]])*62 + squares[2] - adjust2)
+			} else if tbOffDiagonal(squares[2]) != 0 {
+				idx = uint64(6*63*62 + 4*28*62 + (squares[0]>>3)*7*28 + ((squares[1]>>3)-adjust1)*28 + tbMapB1H1H7[squares[2]])
+			} else {
+				idx = uint64(6*63*62 + 4*28*62 + 4*7*28 + (squares[0]>>3)*7*6 + ((squares[1]>>3)-adjust1)*6 + (squares[2] >> 3) - adjust2)
+			}
+		} else {
+			idx = uint64(tbMapKK[tbMapA1D1D4[squares[0]]][squares[1]])
+		}
+	}
+
+	//encode the remaining groups, squares in ascending order. squares are mapped down for each square
+	//already taken by an earlier group.
+	idx *= d.groupIdx[0]
+	groupStart := d.groupLen[0]
+	remainingPawns := t.hasPawns && t.pawnCount[1] > 0
+	for next := 1; d.groupLen[next] != 0; next++ {
+		group := squares[groupStart : groupStart+d.groupLen[next]]
+		sort.Ints(group)
+		n := uint64(0)
+		for i, sq := range group {
+			adjust := 0
+			for _, prev := range squares[:groupStart] {
+				if sq > prev {
+					adjust++
+				}
+			}
+			if remainingPawns {
+				adjust += 8
+			}
+			n += tbBinomial[i+1][sq-adjust]
+		}
+		remainingPawns = false
+		idx += n * d.groupIdx[next]
+		groupStart += d.groupLen[next]
+	}
+
+	return t.mapScore(tbFile, d.decompress(idx), wdl), TB_OK
+}
+
+//probes the WDL or DTZ table for the position's material
+func (tb *tbRegistry) probeTable(p *board.Position, dtz bool, wdl int) (int, tbState) {
+	if board.CountBits(p.Colours[board.WHITE]|p.Colours[board.BLACK]) == 2 { //KvK
+		return 0, TB_OK
+	}
+
+	tables := tb.wdl
+	if dtz {
+		tables = tb.dtz
+	}
+	t, ok := tables[positionMaterialKey(p)]
+	if !ok || !t.load() {
+		return 0, TB_FAIL
+	}
+
+	return t.probe(p, wdl)
+}
+
+//the tables don't store exact values for positions where a capture (or pawn move, for DTZ) is the
+//best move, and know nothing about enpassant. so we have to search those moves ourselves and
+//take the best of them and the table value.
+func (tb *tbRegistry) tbSearch(p *board.Position, checkZeroingMoves bool) (int, tbState) {
+	bestValue := WDL_LOSS
+	moves, _ := board.Movegen(p)
+	moveCount := 0
+
+	for _, m := range moves {
+		if !m.Capture() && (!checkZeroingMoves || m.Piece() != board.PAWN) {
+			continue
+		}
+		moveCount++
+
+		next := *p
+		next.DoMove(m)
+		value, state := tb.tbSearch(&next, false)
+		value = -value
+		if state == TB_FAIL {
+			return WDL_DRAW, TB_FAIL
+		}
+
+		if value > bestValue {
+			bestValue = value
+			if value >= WDL_WIN {
+				return value, TB_ZEROINGBESTMOVE
+			}
+		}
+	}
+
+	//if we searched every move we don't need (and can't trust) the table
+	noMoreMoves := moveCount > 0 && moveCount == len(moves)
+	var value int
+	if noMoreMoves {
+		value = bestValue
+	} else {
+		var state tbState
+		value, state = tb.probeTable(p, false, WDL_DRAW)
+		if state == TB_FAIL {
+			return WDL_DRAW, TB_FAIL
+		}
+	}
+
+	if bestValue >= value {
+		if bestValue > WDL_DRAW || noMoreMoves {
+			return bestValue, TB_ZEROINGBESTMOVE
+		}
+		return bestValue, TB_OK
+	}
+	return value, TB_OK
+}
+
+//probes the WDL tables. result is from the side to move's point of view.
+func (tb *tbRegistry) probeWDL(p *board.Position) (int, tbState) {
+	return tb.tbSearch(p, false)
+}
+
+//DTZ of the move that zeroes the 50 move counter
+func dtzBeforeZeroing(wdl int) int {
+	switch wdl {
+	case WDL_WIN:
+		return 1
+	case WDL_CURSEDWIN:
+		return 101
+	case WDL_BLESSEDLOSS:
+		return -101
+	case WDL_LOSS:
+		return -1
+	}
+	return 0
+}
+
+func sign(n int) int {
+	if n > 0 {
+		return 1
+	} else if n < 0 {
+		return -1
+	}
+	return 0
+}
+
+//probes the DTZ tables. returns the distance to zeroing the 50 move counter in plies, positive for
+//wins and negative for losses. 0 is a draw.
+func (tb *tbRegistry) probeDTZ(p *board.Position) (int, tbState) {
+	wdl, state := tb.tbSearch(p, true)
+	if state == TB_FAIL || wdl == WDL_DRAW {
+		return 0, state
+	}
+
+	if state == TB_ZEROINGBESTMOVE {
+		return dtzBeforeZeroing(wdl), TB_OK
+	}
+
+	dtz, state := tb.probeTable(p, true, wdl)
+	if state == TB_FAIL {
+		return 0, TB_FAIL
+	}
+	if state != TB_CHANGESTM {
+		if wdl == WDL_BLESSEDLOSS || wdl == WDL_CURSEDWIN {
+			dtz += 100
+		}
+		return dtz * sign(wdl), TB_OK
+	}
+
+	//the table only has the other side to move, so do a 1 ply search for the best DTZ
+	minDTZ := 0xFFFF
+	moves, _ := board.Movegen(p)
+	for _, m := range moves {
+		zeroing := m.Capture() || m.Piece() == board.PAWN
+		next := *p
+		next.DoMove(m)
+
+		var value int
+		if zeroing {
+			value, state = tb.tbSearch(&next, false)
+			value = -dtzBeforeZeroing(value)
+		} else {
+			value, state = tb.probeDTZ(&next)
+			value = -value
+		}
+		if state == TB_FAIL {
+			return 0, TB_FAIL
+		}
+
+		//mating moves get a DTZ of 1
+		if value == 1 && next.InCheck() {
+			if replies, _ := board.Movegen(&next); len(replies) == 0 {
+				minDTZ = 1
+			}
+		}
+
+		if !zeroing {
+			value += sign(value)
+		}
+
+		if value < minDTZ && sign(value) == sign(wdl) {
+			minDTZ = value
+		}
+	}
+
+	if minDTZ == 0xFFFF { //no legal moves, we're mated
+		return -1, TB_OK
+	}
+	return minDTZ, TB_OK
+}
+
+//reports whether the position can be probed: standard chess, few enough pieces, and no castling
+func (tb *tbRegistry) probable(p *board.Position) bool {
+	return tb.largest > 0 && p.Variant == board.STANDARD && !(p.CastleWK || p.CastleWQ || p.CastleBK || p.CastleBQ) &&
+		board.CountBits(p.Colours[board.WHITE]|p.Colours[board.BLACK]) <= tb.largest
+}
+
+//WDL probe for use in search. gives a score from the side to move's point of view. we only probe
+//right after captures and pawn moves, since the tables assume a fresh 50 move counter.
+func (e *Engine) tablebaseScore(p *board.Position) (score int, ok bool) {
+	if p.FiftyMoveCounter != 0 || !e.tablebases.probable(p) {
+		return
+	}
+	wdl, state := e.tablebases.probeWDL(p)
+	if state == TB_FAIL {
+		return 0, false
+	}
+	atomic.AddInt64(&e.tbHits, 1)
+
+	switch wdl {
+	case WDL_WIN:
+		return TBWIN, true
+	case WDL_LOSS:
+		return -TBWIN, true
+	}
+	return 0, true //cursed wins and blessed losses are draws under the 50 move rule
+}
+
+//uses the DTZ tables to rank the root moves, and returns the best n as lines. winning moves are ranked
+//by how quickly they make progress (while staying clear of the 50 move rule) and losing moves by how
+//long they hold out. scores are from the side to move's point of view.
+func (e *Engine) tablebaseRootLines(p *board.Position, moves board.MoveList, n int) (lines []Line, ok bool) {
+	if !e.tablebases.probable(p) || len(e.tablebases.dtz) == 0 || len(moves) == 0 {
+		return
+	}
+
+	type rankedMove struct {
+		m         board.Move
+		rank, dtz int
+	}
+	ranked := make([]rankedMove, 0, len(moves))
+	for _, m := range moves {
+		next := *p
+		next.DoMove(m)
+
+		var dtz int
+		var state tbState
+		if next.FiftyMoveCounter == 0 {
+			var wdl int
+			wdl, state = e.tablebases.probeWDL(&next)
+			dtz = dtzBeforeZeroing(-wdl)
+		} else {
+			dtz, state = e.tablebases.probeDTZ(&next)
+			dtz = -dtz
+			dtz += sign(dtz)
+		}
+		if state == TB_FAIL {
+			return nil, false
+		}
+
+		//make sure mating moves get a DTZ of 1
+		if dtz == 2 && next.InCheck() {
+			if replies, _ := board.Movegen(&next); len(replies) == 0 {
+				dtz = 1
+			}
+		}
+
+		rank := 0
+		cnt50 := p.FiftyMoveCounter
+		if dtz > 0 {
+			rank = 1000
+			if dtz+cnt50 > 99 {
+				rank = 1000 - (dtz + cnt50)
+			}
+		} else if dtz < 0 {
+			rank = -1000
+			if -dtz*2+cnt50 >= 100 {
+				rank = -1000 + (-dtz + cnt50)
+			}
+		}
+		ranked = append(ranked, rankedMove{m, rank, dtz})
+	}
+	atomic.AddInt64(&e.tbHits, int64(len(moves)))
+
+	//higher rank is better. for equal ranks, win quickly or lose slowly.
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		return a.rank > b.rank || a.rank == b.rank && (a.dtz > 0 && a.dtz < b.dtz || a.dtz < 0 && a.dtz < b.dtz)
+	})
+	for _, r := range ranked[:min(n, len(ranked))] {
+		//wins and losses that are too far off to beat the 50 move rule are scored as draws
+		score := 0
+		if r.rank >= 900 {
+			score = TBWIN - r.dtz
+		} else if r.rank <= -900 {
+			score = -TBWIN - r.dtz
+		}
+		lines = append(lines, Line{score, Tablebase, board.MoveList{r.m}})
+	}
+	return lines, true
+}
+
+//loads the tablebases in path (a list of directories, like PATH) and reports what we found. an
+//empty path unloads them.
+func (e *Engine) LoadTablebases(path string) string {
+	if path == "" {
+		e.tablebases = tbRegistry{}
+		return "Tablebases disabled."
+	}
+
+	count := e.tablebases.init(path)
+	return fmt.Sprintf("Found %d tablebases (up to %d pieces).", count, e.tablebases.largest)
+}
+
+//probes the WDL tables for the position. result is from the side to move's point of view: 2 for a
+//win, 1 for a win that the 50 move rule makes a draw, 0 for a draw and so on.
+func (e *Engine) ProbeWDL(p *board.Position) (wdl int, ok bool) {
+	wdl, state := e.tablebases.probeWDL(p)
+	return wdl, state != TB_FAIL
+}
+
+//probes the DTZ tables for the position. result is the distance to zeroing the 50 move counter in
+//plies, positive for wins and negative for losses.
+func (e *Engine) ProbeDTZ(p *board.Position) (dtz int, ok bool) {
+	dtz, state := e.tablebases.probeDTZ(p)
+	return dtz, state != TB_FAIL
+}
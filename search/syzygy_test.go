@@ -0,0 +1,120 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+)
+
+//builds a KQvK table where every position has the same value. this tests the table lookup plumbing
+//(material keys, colour flipping, side to move) without needing real tablebase files.
+func TestSyzygySingleValue(t *testing.T) {
+	dir := t.TempDir()
+	data := make([]byte, 64)
+	copy(data, tbWDLMagic)
+	data[4] = 1                                  //flags: split (both sides stored), no pawns
+	data[5] = 0x00                               //group order
+	data[6], data[7], data[8] = 0xEE, 0x66, 0x55 //pieces: k, K, Q for both sides
+	data[9] = 0                                  //alignment
+	data[10], data[11] = TB_SINGLEVALUE, 4       //white to move: win
+	data[12], data[13] = TB_SINGLEVALUE, 0       //black to move: loss
+	if err := os.WriteFile(filepath.Join(dir, "KQvK.rtbw"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var tablebases tbRegistry
+	if count := tablebases.init(dir); count != 1 {
+		t.Fatalf("Expected to find 1 table, found %d", count)
+	}
+
+	tests := []struct {
+		fen string
+		wdl int
+	}{
+		{"8/8/8/8/8/8/1Q6/K6k w - - 0 1", WDL_WIN},
+		{"8/8/8/8/8/8/1Q6/K6k b - - 0 1", WDL_LOSS},
+		{"8/8/8/8/8/8/1q6/k6K b - - 0 1", WDL_WIN},
+		{"8/8/8/8/8/8/1q6/k6K w - - 0 1", WDL_LOSS},
+	}
+	for _, test := range tests {
+		pos := board.NewPosition(test.fen)
+		wdl, state := tablebases.probeWDL(&pos)
+		if state == TB_FAIL || wdl != test.wdl {
+			t.Errorf("%s: expected WDL %d, got %d (state %d)", test.fen, test.wdl, wdl, state)
+		}
+	}
+}
+
+//probes the 3 man tables in test/syzygy. the values are from the rules: mates, stalemates and hanging
+//pieces, and pawns that promote before the king gets there.
+func TestSyzygyProbe(t *testing.T) {
+	engine := NewEngine()
+	tablebases := &engine.tablebases
+	if count := tablebases.init("../test/syzygy"); count != 5 {
+		t.Fatalf("Expected 5 tables in test/syzygy, found %d", count)
+	}
+
+	tests := []struct {
+		fen string
+		wdl int
+		dtz int //0 to skip the DTZ probe
+	}{
+		{"8/8/8/8/8/8/1Q6/K6k w - - 0 1", WDL_WIN, 0},
+		{"8/8/8/8/8/8/1Q6/K6k b - - 0 1", WDL_LOSS, 0},
+		{"8/8/8/8/8/8/1q6/k6K w - - 0 1", WDL_LOSS, 0},
+		{"k7/8/1K6/8/8/8/8/7Q w - - 0 1", WDL_WIN, 1},   //Qh8#
+		{"8/8/8/8/8/8/4Q3/K1k5 b - - 0 1", WDL_DRAW, 0}, //stalemate
+		{"8/8/8/8/8/8/1Q6/K1k5 b - - 0 1", WDL_LOSS, 0}, //check, but the queen is protected
+		{"8/8/8/8/8/8/6Qk/K7 b - - 0 1", WDL_DRAW, 0},   //takes the queen
+		{"8/8/8/8/8/8/8/KR5k b - - 0 1", WDL_LOSS, 0},
+		{"R6k/8/6K1/8/8/8/8/8 b - - 0 1", WDL_LOSS, -1}, //mated
+		{"r6K/8/6k1/8/8/8/8/8 w - - 0 1", WDL_LOSS, -1},
+		{"8/8/8/8/8/4k3/4P3/4K3 w - - 0 1", WDL_DRAW, 0},
+		{"4k3/8/4K3/4P3/8/8/8/8 w - - 0 1", WDL_WIN, 0},
+		{"4k3/8/4K3/4P3/8/8/8/8 b - - 0 1", WDL_LOSS, 0},
+		{"8/4P3/8/8/8/8/k7/4K3 w - - 0 1", WDL_WIN, 1}, //promotes
+		{"8/4P3/8/8/8/8/k7/4K3 b - - 0 1", WDL_LOSS, -2},
+		{"4k3/8/8/8/8/8/4p3/K7 b - - 0 1", WDL_WIN, 1},
+		{"8/8/8/8/8/8/k2K4/8 w - - 0 1", WDL_DRAW, 0}, //KvK
+	}
+	for _, test := range tests {
+		pos := board.NewPosition(test.fen)
+		wdl, ok := engine.ProbeWDL(&pos)
+		if !ok || wdl != test.wdl {
+			t.Errorf("%s: expected WDL %d, got %d (ok %v)", test.fen, test.wdl, wdl, ok)
+		}
+		if test.dtz == 0 {
+			continue
+		}
+		if dtz, ok := engine.ProbeDTZ(&pos); !ok || dtz != test.dtz {
+			t.Errorf("%s: expected DTZ %d, got %d (ok %v)", test.fen, test.dtz, dtz, ok)
+		}
+	}
+
+	//mate in one, the root probe has to find it
+	pos := board.NewPosition("k7/8/1K6/8/8/8/8/7R w - - 0 1")
+	moves, _ := board.Movegen(&pos)
+	lines, ok := engine.tablebaseRootLines(&pos, moves, 1)
+	if !ok || len(lines) != 1 || lines[0].Variation[0].UCIstring() != "h1h8" || lines[0].Score != TBWIN-1 {
+		t.Errorf("Expected root move h1h8 with score %d, got %v", TBWIN-1, lines)
+	}
+
+	//with more lines, the rest of the moves follow from best to worst. giving up the rook draws.
+	pos = board.NewPosition("k7/8/1K6/8/8/8/8/1R6 w - - 0 1")
+	moves, _ = board.Movegen(&pos)
+	lines, _ = engine.tablebaseRootLines(&pos, moves, len(moves))
+	if len(lines) != len(moves) || lines[0].Score <= 0 || lines[len(lines)-1].Score != 0 {
+		t.Fatalf("Expected all %d moves from a win down to a draw, got %v", len(moves), lines)
+	}
+	for i := 1; i < len(lines); i++ {
+		if lines[i].Score > lines[i-1].Score {
+			t.Errorf("Lines out of order: %v", lines)
+		}
+	}
+	result := engine.Analyse(&pos, SearchLimits{Depth: 1, MultiPV: 3}, nil)
+	if len(result.Lines) != 3 {
+		t.Errorf("Expected 3 lines from a tablebase position, got %v", result.Lines)
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+	"github.com/BenNicholls/Aristocrat2/search"
+)
+
+//max depth for perft requests. anything deeper takes long enough to be a DOS
+const MAXSERVERPERFT int = 7
+
+//max time for a search from /analyse or /stream, in msec. searches share the engine and wait for each
+//other, so none can hold it for longer than this.
+const MAXSERVERTIME int = 30000
+
+type analyseRequest struct {
+	FEN      string `json:"fen"`
+	Depth    int    `json:"depth"`
+	MoveTime int    `json:"movetime"` //msec
+	Nodes    int    `json:"nodes"`
+	MultiPV  int    `json:"multipv"`
+}
+
+type analyseResponse struct {
+	FEN string `json:"fen"`
+	search.SearchResult
+}
+
+type perftRequest struct {
+	FEN   string `json:"fen"`
+	Depth int    `json:"depth"`
+}
+
+type perftResponse struct {
+	FEN   string `json:"fen"`
+	Depth int    `json:"depth"`
+	Nodes int    `json:"nodes"`
+	Time  int64  `json:"time"` //msec
+}
+
+type evalRequest struct {
+	FEN string `json:"fen"`
+}
+
+type evalResponse struct {
+	FEN   string `json:"fen"`
+	Score int    `json:"score"` //centipawns, from white's point of view
+}
+
+type legalMovesResponse struct {
+	FEN   string   `json:"fen"`
+	Moves []string `json:"moves"`
+}
+
+//server handles the requests for serve mode. analysis is done by the server's own engine, one search
+//at a time: /analyse and /stream searches queue up behind the one that's running, whoever asked for it.
+//each search stops after MAXSERVERTIME at the latest, so the wait is never longer than that.
+type server struct {
+	*http.ServeMux
+	engine *search.Engine
+}
+
+//builds the handler for serve mode
+func newServer(hashSize, threads int) *server {
+	s := &server{http.NewServeMux(), search.NewEngine()}
+	s.engine.SetHashSize(hashSize)
+	s.engine.Threads = threads
+	s.HandleFunc("/analyse", s.serveAnalyse)
+	s.HandleFunc("/perft", s.servePerft)
+	s.HandleFunc("/eval", s.serveEval)
+	s.HandleFunc("/legalmoves", serveLegalMoves)
+	s.HandleFunc("/stream", s.serveStream)
+	return s
+}
+
+func (s *server) serveAnalyse(w http.ResponseWriter, r *http.Request) {
+	var req analyseRequest
+	if !readRequest(w, r, &req) {
+		return
+	}
+	pos, err := req.position()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result := s.engine.Analyse(&pos, req.limits(r.Context().Done()), nil)
+	writeJSON(w, http.StatusOK, analyseResponse{req.FEN, result})
+}
+
+//checks the request and sets up the position to analyse
+func (req analyseRequest) position() (pos board.Position, err error) {
+	if req.Depth < 0 || req.MoveTime < 0 || req.Nodes < 0 || req.MultiPV < 0 {
+		return pos, errors.New("depth, movetime, nodes and multipv can't be negative")
+	}
+	return board.ParseFEN(req.FEN)
+}
+
+//the limits asked for, with the time capped at MAXSERVERTIME
+func (req analyseRequest) limits(stop <-chan struct{}) search.SearchLimits {
+	limits := search.SearchLimits{Depth: req.Depth, MoveTime: req.MoveTime, Nodes: req.Nodes, MultiPV: req.MultiPV, Stop: stop}
+	if limits.Depth == 0 && limits.MoveTime == 0 && limits.Nodes == 0 {
+		limits.Depth = 8 //what the engine would search to anyway
+	}
+	if limits.MoveTime == 0 || limits.MoveTime > MAXSERVERTIME {
+		limits.MoveTime = MAXSERVERTIME
+	}
+	return limits
+}
+
+func (s *server) servePerft(w http.ResponseWriter, r *http.Request) {
+	var req perftRequest
+	if !readRequest(w, r, &req) {
+		return
+	}
+	pos, err := board.ParseFEN(req.FEN)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Depth < 1 || req.Depth > MAXSERVERPERFT {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("depth must be between 1 and %d", MAXSERVERPERFT))
+		return
+	}
+
+	startTime := time.Now()
+	nodes, stopped := board.PerftUntil(&pos, req.Depth, s.engine.PerftCache(), r.Context().Done())
+	if stopped { //the client's gone, there's no one to answer
+		return
+	}
+	writeJSON(w, http.StatusOK, perftResponse{req.FEN, req.Depth, nodes, time.Since(startTime).Milliseconds()})
+}
+
+func (s *server) serveEval(w http.ResponseWriter, r *http.Request) {
+	var req evalRequest
+	if !readRequest(w, r, &req) {
+		return
+	}
+	pos, err := board.ParseFEN(req.FEN)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, evalResponse{req.FEN, s.engine.Evaluator().Eval(&pos) * board.ScoreModifier[pos.ToMove]})
+}
+
+func serveLegalMoves(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	fen := r.URL.Query().Get("fen")
+	pos, err := board.ParseFEN(fen)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	moves, _ := board.Movegen(&pos)
+	writeJSON(w, http.StatusOK, legalMovesResponse{fen, moves.UCImoves()})
+}
+
+//decodes the JSON body of a POST request into req. writes an error response and returns false if
+//that doesn't work.
+func readRequest(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return false
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(req); err != nil {
+		writeError(w, http.StatusBadRequest, "could not read request: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+//messages to /stream. the client sends {"type": "analyse", ...} with the same fields as /analyse, and
+//{"type": "stop"} to end the search early. the search is reported back with a JSONReporter.
+type streamRequest struct {
+	Type string `json:"type"`
+	analyseRequest
+}
+
+//streams analysis over a websocket. one search runs at a time per connection; a new analyse
+//request while one is running is an error.
+func (s *server) serveStream(w http.ResponseWriter, r *http.Request) {
+	ws, err := upgradeWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	defer ws.close()
+	reporter := search.NewJSONReporter(ws)
+
+	var stop, done chan struct{}
+	stopSearch := func() {
+		if stop != nil {
+			close(stop)
+			stop = nil
+		}
+	}
+	searching := func() bool {
+		if done == nil {
+			return false
+		}
+		select {
+		case <-done:
+			return false
+		default:
+			return true
+		}
+	}
+
+	for {
+		msg, err := ws.readMessage()
+		if err != nil {
+			//client's gone. stop the search and wait for it so nothing writes to a closed connection
+			stopSearch()
+			if done != nil {
+				<-done
+			}
+			return
+		}
+
+		var req streamRequest
+		if err := json.Unmarshal(msg, &req); err != nil {
+			reporter.Error("could not read request: " + err.Error())
+			continue
+		}
+		switch req.Type {
+		case "analyse":
+			if searching() {
+				reporter.Error("already analysing. send stop first")
+				break
+			}
+			pos, err := req.position()
+			if err != nil {
+				reporter.Error(err.Error())
+				break
+			}
+			stopSearch()
+			stop, done = make(chan struct{}), make(chan struct{})
+			go func(limits search.SearchLimits, done chan struct{}) {
+				s.engine.Analyse(&pos, limits, reporter)
+				close(done)
+			}(req.limits(stop), done)
+		case "stop":
+			stopSearch()
+		default:
+			reporter.Error("unknown message type: " + req.Type)
+		}
+	}
+}
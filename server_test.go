@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BenNicholls/Aristocrat2/search"
+)
+
+func TestServer(t *testing.T) {
+	server := httptest.NewServer(newServer(search.DEFAULTHASHSIZE, 1))
+	defer server.Close()
+
+	post := func(path, body string, resp interface{}) int {
+		r, err := http.Post(server.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Body.Close()
+		if err := json.NewDecoder(r.Body).Decode(resp); err != nil {
+			t.Fatal(err)
+		}
+		return r.StatusCode
+	}
+
+	var moves legalMovesResponse
+	r, err := http.Get(server.URL + "/legalmoves?fen=" + url.QueryEscape("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.NewDecoder(r.Body).Decode(&moves)
+	r.Body.Close()
+	if len(moves.Moves) != 48 {
+		t.Errorf("Expected 48 legal moves, got %d", len(moves.Moves))
+	}
+
+	var perft perftResponse
+	if status := post("/perft", `{"fen": "startpos", "depth": 5}`, &perft); status != http.StatusOK || perft.Nodes != 4865609 {
+		t.Errorf("Expected perft 5 = 4865609, got %d (status %d)", perft.Nodes, status)
+	}
+
+	var ev evalResponse
+	if status := post("/eval", `{"fen": "4k3/8/8/8/8/8/8/3QK3 b - - 0 1"}`, &ev); status != http.StatusOK || ev.Score <= 0 {
+		t.Errorf("Expected positive eval for white with an extra queen, got %d (status %d)", ev.Score, status)
+	}
+
+	var analysis analyseResponse
+	if status := post("/analyse", `{"fen": "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "depth": 3, "multipv": 2}`, &analysis); status != http.StatusOK {
+		t.Fatalf("Analyse failed with status %d", status)
+	}
+	if analysis.BestMove != "a1a8" || len(analysis.Lines) != 2 || analysis.Lines[0].Result != "checkmate" {
+		t.Errorf("Expected mate with a1a8 and 2 lines, got %+v", analysis)
+	}
+
+	var failed map[string]string
+	if status := post("/analyse", `{"fen": "not a fen"}`, &failed); status != http.StatusBadRequest || failed["error"] == "" {
+		t.Errorf("Expected an error for a bad FEN, got status %d", status)
+	}
+
+	//searches share the engine, so none gets to run for longer than MAXSERVERTIME
+	for _, req := range []analyseRequest{{Depth: 100}, {MoveTime: 10 * MAXSERVERTIME}, {}} {
+		if limits := req.limits(nil); limits.MoveTime != MAXSERVERTIME || limits.Depth == 0 && limits.Nodes == 0 && req.MoveTime == 0 {
+			t.Errorf("%+v searches with %+v", req, limits)
+		}
+	}
+}
+
+//a request that's been cancelled should stop the search straight away
+func TestServerCancel(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodPost, "/analyse", strings.NewReader(`{"fen": "startpos", "depth": 100}`)).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	s := newServer(search.DEFAULTHASHSIZE, 1)
+	startTime := time.Now()
+	s.ServeHTTP(rec, req)
+	if time.Since(startTime) > time.Second {
+		t.Errorf("Cancelled search took %v", time.Since(startTime))
+	}
+	if s.engine.Calculating() {
+		t.Error("Controller still calculating after the request finished")
+	}
+
+	//perft too
+	req = httptest.NewRequest(http.MethodPost, "/perft", strings.NewReader(`{"fen": "startpos", "depth": 7}`)).WithContext(ctx)
+	startTime = time.Now()
+	s.ServeHTTP(httptest.NewRecorder(), req)
+	if time.Since(startTime) > time.Second {
+		t.Errorf("Cancelled perft took %v", time.Since(startTime))
+	}
+}
+
+//perft on the server mustn't leave anything behind for the next analysis to trip over
+func TestServerPerftThenAnalyse(t *testing.T) {
+	request := func(s *server, path, body string, resp interface{}) {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		if err := json.NewDecoder(rec.Body).Decode(resp); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("%s failed with status %d: %v", path, rec.Code, err)
+		}
+	}
+
+	used, clean := newServer(search.DEFAULTHASHSIZE, 1), newServer(search.DEFAULTHASHSIZE, 1)
+	var perft perftResponse
+	request(used, "/perft", `{"fen": "startpos", "depth": 4}`, &perft)
+
+	var after, expected analyseResponse
+	request(used, "/analyse", `{"fen": "startpos", "depth": 3}`, &after)
+	request(clean, "/analyse", `{"fen": "startpos", "depth": 3}`, &expected)
+	if after.BestMove != expected.BestMove || after.Lines[0].Score != expected.Lines[0].Score {
+		t.Errorf("Analysis after perft gave %s (%d), expected %s (%d)", after.BestMove, after.Lines[0].Score, expected.BestMove, expected.Lines[0].Score)
+	}
+}
+
+func TestServerStream(t *testing.T) {
+	server := httptest.NewServer(newServer(search.DEFAULTHASHSIZE, 1))
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef"))
+	fmt.Fprintf(conn, "GET /stream HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n", key)
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha1.Sum([]byte(key + WEBSOCKETGUID))
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(hash[:]) {
+		t.Fatalf("Bad handshake: %s", resp.Status)
+	}
+
+	//client frames have to be masked
+	send := func(msg string) {
+		frame := []byte{0x80 | WS_TEXT, 0x80 | byte(len(msg)), 1, 2, 3, 4}
+		for i := range msg {
+			frame = append(frame, msg[i]^frame[2+i%4])
+		}
+		conn.Write(frame)
+	}
+	//the parts of the reporter's messages we look at
+	type streamMessage struct {
+		Type     string `json:"type"`
+		Message  string `json:"message"`
+		Depth    int    `json:"depth"`
+		BestMove string `json:"bestmove"`
+	}
+	//reads messages until a bestmove, returning the infos and the bestmove
+	receive := func() (infos []streamMessage, best streamMessage) {
+		for {
+			header := make([]byte, 2)
+			if _, err := io.ReadFull(reader, header); err != nil {
+				t.Fatal(err)
+			}
+			length := int(header[1])
+			if length == 126 {
+				ext := make([]byte, 2)
+				io.ReadFull(reader, ext)
+				length = int(binary.BigEndian.Uint16(ext))
+			}
+			payload := make([]byte, length)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				t.Fatal(err)
+			}
+
+			var msg streamMessage
+			json.Unmarshal(payload, &msg)
+			switch msg.Type {
+			case "info":
+				infos = append(infos, msg)
+			case "bestmove":
+				best = msg
+				return
+			case "error":
+				t.Fatal("Error from server:", msg.Message)
+			}
+		}
+	}
+
+	send(`{"type": "analyse", "fen": "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "depth": 3}`)
+	infos, best := receive()
+	if len(infos) == 0 || infos[0].Depth != 1 || best.BestMove != "a1a8" {
+		t.Errorf("Expected infos and bestmove a1a8, got %d infos and %s", len(infos), best.BestMove)
+	}
+
+	startTime := time.Now()
+	send(`{"type": "analyse", "fen": "startpos", "depth": 100}`)
+	time.Sleep(100 * time.Millisecond)
+	send(`{"type": "stop"}`)
+	if _, best = receive(); best.BestMove == "" || time.Since(startTime) > 5*time.Second {
+		t.Errorf("Stop didn't end the search properly. bestmove %s after %v", best.BestMove, time.Since(startTime))
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+
+	"github.com/BenNicholls/Aristocrat2/match"
+)
+
+//progress of an SPRT, saved after every game pair so an interrupted test can carry on where it left off
+type sprtState struct {
+	Elo0, Elo1  float64
+	Alpha, Beta float64
+	Rounds      int //games played, always a whole number of pairs
+	Score       match.Score
+	Pentanomial [5]int
+	PGNSize     int64 //bytes of the PGN file taken up by those games. anything after is from an unfinished pair
+}
+
+func loadSPRTState(filename string) (state sprtState, err error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(data, &state)
+	return
+}
+
+//writes to a temporary file first, so a crash can't leave a half written state
+func (state sprtState) save(filename string) error {
+	data, err := json.MarshalIndent(state, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+//runs the sprt command: aristocrat2 sprt -engine1 <new build> -engine2 <old build> [options]. plays game
+//pairs until the test says whether the first engine is elo1 stronger than the second (H1) or only elo0
+//stronger (H0). progress is kept in the -state file; run the same command again to resume.
+func runSPRT(args []string) int {
+	flags := flag.NewFlagSet("sprt", flag.ExitOnError)
+	matchOptions := addMatchFlags(flags)
+	var test match.SPRT
+	flags.Float64Var(&test.Elo0, "elo0", 0, "Elo difference for H0")
+	flags.Float64Var(&test.Elo1, "elo1", 5, "Elo difference for H1")
+	flags.Float64Var(&test.Alpha, "alpha", 0.05, "chance of accepting H1 when H0 is true")
+	flags.Float64Var(&test.Beta, "beta", 0.05, "chance of accepting H0 when H1 is true")
+	maxGames := flags.Int("maxgames", 0, "give up after this many games (0 for no limit)")
+	stateFile := flags.String("state", "sprt.json", "file to keep progress in. an existing one is resumed")
+	pgnFile := flags.String("pgn", "sprt.pgn", "file to write the games to")
+	flags.Parse(args)
+
+	if test.Elo1 <= test.Elo0 || test.Alpha <= 0 || test.Alpha >= 1 || test.Beta <= 0 || test.Beta >= 1 {
+		fmt.Fprintln(os.Stderr, "Need elo0 < elo1, and alpha and beta between 0 and 1")
+		return 2
+	}
+	state := sprtState{Elo0: test.Elo0, Elo1: test.Elo1, Alpha: test.Alpha, Beta: test.Beta}
+	resuming := false
+	if saved, err := loadSPRTState(*stateFile); err == nil {
+		if saved.Elo0 != state.Elo0 || saved.Elo1 != state.Elo1 || saved.Alpha != state.Alpha || saved.Beta != state.Beta {
+			fmt.Fprintf(os.Stderr, "%s is for a different test [%g, %g] alpha %g beta %g\n", *stateFile, saved.Elo0, saved.Elo1, saved.Alpha, saved.Beta)
+			return 2
+		}
+		state, resuming = saved, true
+		test.Pentanomial = state.Pentanomial
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintln(os.Stderr, "Could not read state:", err)
+		return 1
+	}
+
+	m, err := matchOptions.newMatch()
+	defer closePlayers(m)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	m.Round, m.Games = state.Rounds, *maxGames
+	if m.Games == 0 {
+		m.Games = math.MaxInt32
+	}
+
+	//the games of a pair that was interrupted get played again, so they're cut off the end
+	pgn, err := os.OpenFile(*pgnFile, os.O_CREATE|os.O_WRONLY, 0644)
+	if err == nil {
+		if err = pgn.Truncate(state.PGNSize); err == nil {
+			_, err = pgn.Seek(state.PGNSize, io.SeekStart)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not open PGN file:", err)
+		return 1
+	}
+	defer pgn.Close()
+	m.PGN = pgn
+
+	fmt.Printf("SPRT: %s vs %s at %s. H0: %g Elo, H1: %g Elo, alpha %g, beta %g\n", m.Players[0].Name(), m.Players[1].Name(), m.TimeControl, test.Elo0, test.Elo1, test.Alpha, test.Beta)
+	if resuming {
+		fmt.Printf("Resuming after %d games\n", state.Rounds)
+		reportSPRT(state, test)
+	}
+
+	var pair match.Score
+	m.GameOver = func(g match.Game, _ match.Score) bool {
+		pair.Add(g, g.Round%2 == 1)
+		if g.Round%2 == 1 {
+			return false
+		}
+
+		//pairs with an unfinished game are left out, so the pentanomial counts stay meaningful
+		if pair.Games() == 2 {
+			test.Add(pair.Points() * 2)
+			state.Score.Wins += pair.Wins
+			state.Score.Draws += pair.Draws
+			state.Score.Losses += pair.Losses
+		}
+		pair = match.Score{}
+		state.Rounds, state.Pentanomial = g.Round, test.Pentanomial
+		state.PGNSize, _ = pgn.Seek(0, io.SeekCurrent)
+		if err := state.save(*stateFile); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not save state:", err)
+		}
+		reportSPRT(state, test)
+		done, _ := test.Result()
+		return done
+	}
+	if done, _ := test.Result(); !done {
+		if _, err := m.Run(); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not write PGN:", err)
+			return 1
+		}
+	}
+
+	if done, accepted := test.Result(); !done {
+		fmt.Println("No result: out of games")
+	} else if accepted {
+		fmt.Printf("H1 accepted: %s is at least %g Elo stronger\n", m.Players[0].Name(), test.Elo1)
+	} else {
+		fmt.Printf("H0 accepted: %s is not %g Elo stronger\n", m.Players[0].Name(), test.Elo1)
+	}
+	return 0
+}
+
+func reportSPRT(state sprtState, test match.SPRT) {
+	lower, upper := test.Bounds()
+	diff, margin := test.Elo()
+	fmt.Printf("Games: %d, %s\n", state.Rounds, state.Score)
+	fmt.Printf("Pentanomial: %v. Elo: %.1f +/- %.1f. LLR: %.2f (%.2f, %.2f) [%g, %g]\n", test.Pentanomial, diff, margin, test.LLR(), lower, upper, test.Elo0, test.Elo1)
+}
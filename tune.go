@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/BenNicholls/Aristocrat2/eval"
+	"github.com/BenNicholls/Aristocrat2/tune"
+)
+
+//runs the tune command: aristocrat2 tune -data <positions> [options]. fits K to the current weights,
+//tunes them and writes them out in a weights file the engine can load with -weights or the WeightsFile
+//option.
+func runTune(args []string) int {
+	flags := flag.NewFlagSet("tune", flag.ExitOnError)
+	data := flags.String("data", "", "file of quiet positions labelled with game results")
+	start := flags.String("weights", "", "weights file to start from, instead of the built in weights")
+	method := flags.String("method", "adam", "optimiser: adam (gradient descent) or local (coordinate descent)")
+	epochs := flags.Int("epochs", 2000, "epochs for adam")
+	rate := flags.Float64("rate", 1, "learning rate for adam, in centipawns")
+	passes := flags.Int("passes", 100, "max passes over the weights for local")
+	threads := flags.Int("threads", runtime.NumCPU(), "number of threads")
+	out := flags.String("out", "weights.txt", "file to write the tuned weights to")
+	flags.Parse(args)
+
+	if *data == "" {
+		fmt.Fprintln(os.Stderr, "tune needs -data")
+		return 2
+	}
+	if *method != "adam" && *method != "local" {
+		fmt.Fprintln(os.Stderr, "Unknown method:", *method)
+		return 2
+	}
+	startWeights := eval.DefaultWeights
+	if *start != "" {
+		w, err := eval.LoadWeights(*start)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not load weights:", err)
+			return 1
+		}
+		startWeights = w
+	}
+	positions, err := tune.LoadPositions(*data)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load positions:", err)
+		return 1
+	}
+	if len(positions) == 0 {
+		fmt.Fprintln(os.Stderr, "No positions in", *data)
+		return 1
+	}
+
+	tuner := tune.NewTuner(positions)
+	tuner.Threads = *threads
+	tuner.Progress = func(epoch int, e float64) {
+		fmt.Printf("%d: error %.6f\n", epoch, e)
+	}
+	weights := tune.ToFloats(startWeights)
+	fmt.Printf("%d positions. K: %.4f, error %.6f\n", len(positions), tuner.FitK(weights), tuner.Error(weights))
+
+	if *method == "adam" {
+		weights = tuner.Adam(weights, *epochs, *rate)
+	} else {
+		weights = tuner.LocalSearch(weights, *passes)
+	}
+	tuned := tune.ToWeights(weights)
+	fmt.Printf("Tuned error %.6f\n", tuner.Error(tune.ToFloats(tuned)))
+	tuned.WriteTo(os.Stdout)
+
+	file, err := os.Create(*out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not create weights file:", err)
+		return 1
+	}
+	defer file.Close()
+	if _, err := tuned.WriteTo(file); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not write weights:", err)
+		return 1
+	}
+	fmt.Println("Weights written to", *out)
+	return 0
+}
@@ -0,0 +1,267 @@
+//Package tune fits the evaluation weights to game results, Texel style: the eval of each position is
+//turned into an expected score with a sigmoid, and the weights are adjusted to minimise the mean
+//squared error against the results of the games the positions came from.
+package tune
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+	"github.com/BenNicholls/Aristocrat2/eval"
+)
+
+//one use of a weight in a position's eval
+type term struct {
+	weight int32
+	count  int32
+}
+
+//Position is a training position: the eval's terms and the game result
+type Position struct {
+	terms  []term  //only the weights that are used
+	result float64 //1 for a white win, 0.5 for a draw, 0 for a black win
+}
+
+//reads positions labelled with results. each line has a FEN (the move counters are optional) and a
+//result somewhere after it, as 1-0/0-1/1/2-1/2 or 1.0/0.5/0.0, in quotes or brackets or not. positions
+//should be quiet, since the tuner doesn't search.
+func LoadPositions(filename string) ([]Position, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadPositions(file)
+}
+
+func ReadPositions(r io.Reader) (positions []Position, err error) {
+	lines := bufio.NewScanner(r)
+	for lineNumber := 1; lines.Scan(); lineNumber++ {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(strings.NewReplacer(";", " ", "|", " ", ",", " ").Replace(line))
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("line %d: expected a FEN and a result", lineNumber)
+		}
+		result, ok := -1.0, false
+		for _, field := range fields[4:] {
+			if result, ok = parseResult(field); ok {
+				break
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("line %d: no result", lineNumber)
+		}
+		pos, err := board.ParseFEN(strings.Join(fields[:4], " "))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		positions = append(positions, newPosition(&pos, result))
+	}
+	return positions, lines.Err()
+}
+
+func parseResult(s string) (result float64, ok bool) {
+	switch strings.Trim(s, `"[]`) {
+	case "1-0", "1.0":
+		return 1, true
+	case "1/2-1/2", "0.5":
+		return 0.5, true
+	case "0-1", "0.0":
+		return 0, true
+	}
+	return
+}
+
+func newPosition(p *board.Position, result float64) (pos Position) {
+	pos.result = result
+	for i, count := range eval.Coefficients(p) {
+		if count != 0 {
+			pos.terms = append(pos.terms, term{int32(i), int32(count)})
+		}
+	}
+	return
+}
+
+//eval of the position with the weights, from white's point of view
+func (p *Position) eval(weights []float64) (score float64) {
+	for _, t := range p.terms {
+		score += weights[t.weight] * float64(t.count)
+	}
+	return
+}
+
+//the expected score for an eval
+func sigmoid(k, score float64) float64 {
+	return 1 / (1 + math.Pow(10, -k*score/400))
+}
+
+//runs f on every position, split over the threads, and adds up the results. f gets the thread number so
+//it can keep its own running totals.
+func parallel(positions []Position, threads int, f func(thread int, p *Position) float64) (total float64) {
+	threads = max(1, min(threads, len(positions)))
+	totals := make([]float64, threads)
+	var wg sync.WaitGroup
+	for t := 0; t < threads; t++ {
+		wg.Add(1)
+		go func(t int) {
+			defer wg.Done()
+			for i := t; i < len(positions); i += threads {
+				totals[t] += f(t, &positions[i])
+			}
+		}(t)
+	}
+	wg.Wait()
+	for _, subtotal := range totals {
+		total += subtotal
+	}
+	return
+}
+
+//Tuner fits weights to a set of positions
+type Tuner struct {
+	Positions []Position
+	Threads   int
+	K         float64                    //sigmoid scaling. set by FitK
+	Progress  func(epoch int, e float64) //called now and then while optimising. can be nil
+}
+
+func NewTuner(positions []Position) *Tuner {
+	return &Tuner{Positions: positions, Threads: runtime.NumCPU(), K: 1}
+}
+
+//Error is the mean squared error of the predictions from the weights
+func (t *Tuner) Error(weights []float64) float64 {
+	return t.errorWithK(weights, t.K)
+}
+
+func (t *Tuner) errorWithK(weights []float64, k float64) float64 {
+	total := parallel(t.Positions, t.Threads, func(_ int, p *Position) float64 {
+		return math.Pow(p.result-sigmoid(k, p.eval(weights)), 2)
+	})
+	return total / float64(len(t.Positions))
+}
+
+//FitK finds the sigmoid scaling that best fits the weights as they are, so the tuning that follows
+//doesn't just rescale them
+func (t *Tuner) FitK(weights []float64) float64 {
+	//the error is unimodal in k, so a golden section search homes in on it
+	lo, hi := 0.0, 10.0
+	ratio := (math.Sqrt(5) - 1) / 2
+	for hi-lo > 1e-4 {
+		a, b := hi-ratio*(hi-lo), lo+ratio*(hi-lo)
+		if t.errorWithK(weights, a) < t.errorWithK(weights, b) {
+			hi = b
+		} else {
+			lo = a
+		}
+	}
+	t.K = (lo + hi) / 2
+	return t.K
+}
+
+//gradient of the error with respect to each weight
+func (t *Tuner) gradient(weights []float64) []float64 {
+	threads := max(1, min(t.Threads, len(t.Positions)))
+	partial := make([][]float64, threads)
+	for i := range partial {
+		partial[i] = make([]float64, len(weights))
+	}
+	parallel(t.Positions, threads, func(thread int, p *Position) float64 {
+		s := sigmoid(t.K, p.eval(weights))
+		//d/dw of (r - s)^2, where ds/d(eval) = s(1-s) * k ln10/400
+		g := (s - p.result) * s * (1 - s)
+		for _, use := range p.terms {
+			partial[thread][use.weight] += g * float64(use.count)
+		}
+		return 0
+	})
+
+	grad := make([]float64, len(weights))
+	scale := 2 * t.K * math.Ln10 / 400 / float64(len(t.Positions))
+	for _, p := range partial {
+		for i := range grad {
+			grad[i] += p[i] * scale
+		}
+	}
+	return grad
+}
+
+//Adam optimises the weights by gradient descent for the number of epochs, with the Adam update rule.
+//the learning rate is in centipawns; 1 is a good start.
+func (t *Tuner) Adam(weights []float64, epochs int, rate float64) []float64 {
+	const beta1, beta2, epsilon = 0.9, 0.999, 1e-8
+	w := append([]float64{}, weights...)
+	m, v := make([]float64, len(w)), make([]float64, len(w))
+	for epoch := 1; epoch <= epochs; epoch++ {
+		grad := t.gradient(w)
+		for i := range w {
+			m[i] = beta1*m[i] + (1-beta1)*grad[i]
+			v[i] = beta2*v[i] + (1-beta2)*grad[i]*grad[i]
+			mHat := m[i] / (1 - math.Pow(beta1, float64(epoch)))
+			vHat := v[i] / (1 - math.Pow(beta2, float64(epoch)))
+			w[i] -= rate * mHat / (math.Sqrt(vHat) + epsilon)
+		}
+		if t.Progress != nil && (epoch%100 == 0 || epoch == epochs) {
+			t.Progress(epoch, t.Error(w))
+		}
+	}
+	return w
+}
+
+//LocalSearch optimises the weights by coordinate descent: each weight is nudged up or down a centipawn
+//while that helps, until no single nudge does or the passes run out.
+func (t *Tuner) LocalSearch(weights []float64, passes int) []float64 {
+	w := append([]float64{}, weights...)
+	best := t.Error(w)
+	for pass := 1; pass <= passes; pass++ {
+		improved := false
+		for i := range w {
+			for _, step := range []float64{1, -1} {
+				for {
+					w[i] += step
+					if e := t.Error(w); e < best {
+						best, improved = e, true
+					} else {
+						w[i] -= step
+						break
+					}
+				}
+			}
+		}
+		if t.Progress != nil {
+			t.Progress(pass, best)
+		}
+		if !improved {
+			break
+		}
+	}
+	return w
+}
+
+//converts weights for the tuner
+func ToFloats(w eval.Weights) []float64 {
+	f := make([]float64, len(w))
+	for i, value := range w {
+		f[i] = float64(value)
+	}
+	return f
+}
+
+//rounds tuned weights back to centipawns
+func ToWeights(f []float64) eval.Weights {
+	w := make(eval.Weights, len(f))
+	for i, value := range f {
+		w[i] = int(math.Round(value))
+	}
+	return w
+}
@@ -0,0 +1,69 @@
+package tune
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+	"github.com/BenNicholls/Aristocrat2/eval"
+)
+
+//positions with random material missing, labelled with the expected score from known weights, so a
+//good tuner should find those weights again
+func syntheticPositions(n int, weights []float64, k float64) (positions []Position) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < n; i++ {
+		pos := board.NewPosition("")
+		for square := 0; square < 64; square++ {
+			piece := pos.GetPieceOnSquare(square)
+			if piece >= 0 && piece != board.KING && rng.Intn(3) == 0 {
+				colour := board.WHITE
+				if board.CheckBit(pos.Colours[board.BLACK], square) {
+					colour = board.BLACK
+				}
+				pos.RemovePiece(colour, piece, square)
+			}
+		}
+		p := newPosition(&pos, 0)
+		p.result = sigmoid(k, p.eval(weights))
+		positions = append(positions, p)
+	}
+	return
+}
+
+func TestTune(t *testing.T) {
+	truth := []float64{90, 310, 330, 480, 950}
+	tuner := NewTuner(syntheticPositions(2000, truth, 1.5))
+	if k := tuner.FitK(truth); math.Abs(k-1.5) > 0.01 {
+		t.Errorf("Expected K of 1.5, got %.4f", k)
+	}
+
+	tuner.K = 1.5
+	tuned := tuner.Adam(ToFloats(eval.DefaultWeights), 2000, 2)
+	for i := range truth {
+		if math.Abs(tuned[i]-truth[i]) > 5 {
+			t.Errorf("%s: expected %.0f, got %.1f", eval.WeightNames[i], truth[i], tuned[i])
+		}
+	}
+
+	start := ToFloats(eval.DefaultWeights)
+	if local := tuner.LocalSearch(start, 5); tuner.Error(local) >= tuner.Error(start) {
+		t.Error("Local search didn't improve the weights")
+	}
+}
+
+func TestReadPositions(t *testing.T) {
+	data := `rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 [0.5]
+4k3/8/8/8/8/8/8/3QK3 w - - c9 "1-0";
+4k3/8/8/8/8/8/8/3QK3 b - - 0 1 | 850 | 0.0
+`
+	positions, err := ReadPositions(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != 3 || positions[0].result != 0.5 || positions[1].result != 1 || positions[2].result != 0 {
+		t.Errorf("Results read wrong: %v", positions)
+	}
+}
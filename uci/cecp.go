@@ -0,0 +1,283 @@
+package uci
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+	"github.com/BenNicholls/Aristocrat2/search"
+)
+
+//CECPinterface speaks the XBoard/WinBoard protocol (CECP). unlike UCI the engine keeps track of the
+//game itself and plays its own moves, so the interface remembers the moves played since the last
+//new/setboard to support undo.
+type CECPinterface struct {
+	*Engine
+
+	force      bool //force mode: just play the moves we're given, don't think
+	engineSide int  //colour the engine is playing
+	post       bool //print thinking output
+
+	startFEN string
+	moves    board.MoveList
+	seen     map[uint64]int //times each position has come up, for spotting repetitions
+
+	//time control
+	movesPerSession int //0 for the whole game
+	increment       int //msec
+	moveTime        int //msec, from st. overrides the clock
+	maxDepth        int //from sd. 0 for no limit
+	clock           int //msec left on our clock
+}
+
+//the variants by the names xboard has for them. antichess is giveaway: kings are just pieces, and having
+//no moves wins. king of the hill has no xboard name, so it goes by the one other engines use.
+var cecpVariantNames = [board.NUMVARIANTS]string{"normal", "3check", "kingofthehill", "giveaway", "atomic", "crazyhouse"}
+
+func parseCECPVariant(name string) (board.Variant, bool) {
+	for v, n := range cecpVariantNames {
+		if n == name {
+			return board.Variant(v), true
+		}
+	}
+	return board.STANDARD, false
+}
+
+func (c *CECPinterface) processCommand(cmd, params string) string {
+	switch cmd {
+	case "xboard":
+	case "protover":
+		variants := strings.Join(cecpVariantNames[:], ",")
+		fmt.Println("feature myname=\"Aristocrat\" ping=1 setboard=1 usermove=1 time=1 draw=0 sigint=0 sigterm=0 reuse=1 analyze=0 colors=0 variants=\"" + variants + "\" done=1")
+	case "accepted", "rejected", "random", "hard", "easy", "computer", "name", "rating":
+	case "variant": //comes after new, which sets up normal chess
+		v, ok := parseCECPVariant(params)
+		if !ok {
+			fmt.Println("Error (unsupported variant):", params)
+			break
+		}
+		c.variant = v
+		c.setBoard("")
+	case "new":
+		c.cancelSearch()
+		c.variant = board.STANDARD
+		c.setBoard("")
+		c.force = false
+		c.engineSide = board.BLACK
+		c.maxDepth = 0
+		c.moveTime = 0
+	case "setboard":
+		c.cancelSearch()
+		if err := c.setBoard(params); err != nil {
+			fmt.Println("tellusererror Illegal position:", err)
+		}
+	case "quit":
+		return "quit"
+	case "force":
+		c.cancelSearch()
+		c.force = true
+	case "go":
+		c.force = false
+		c.engineSide = c.game.ToMove
+		c.think()
+	case "playother":
+		c.force = false
+		c.engineSide = board.Opponent(c.game.ToMove)
+	case "usermove":
+		m, ok := board.ParseMove(&c.game, strings.TrimSpace(params))
+		if !ok {
+			fmt.Println("Illegal move:", params)
+			break
+		}
+		c.play(m)
+		if !c.force && c.game.ToMove == c.engineSide && !c.gameOver() {
+			c.think()
+		}
+	case "?":
+		c.search.Stop()
+	case "ping":
+		fmt.Println("pong", params)
+	case "level": //level <moves per session> <base time in minutes[:seconds]> <increment in seconds>
+		args := strings.Fields(params)
+		if len(args) != 3 {
+			fmt.Println("Error (bad level):", params)
+			break
+		}
+		//the base time is ignored, we get told what's on the clock before each move anyway
+		c.movesPerSession, _ = strconv.Atoi(args[0])
+		if inc, err := strconv.ParseFloat(args[2], 64); err == nil {
+			c.increment = int(inc * 1000)
+		}
+		c.moveTime = 0
+	case "st":
+		if t, err := strconv.ParseFloat(params, 64); err == nil {
+			c.moveTime = int(t * 1000)
+		}
+	case "sd":
+		if d, err := strconv.Atoi(params); err == nil {
+			c.maxDepth = d
+		}
+	case "time": //our clock, in centiseconds
+		if t, err := strconv.Atoi(params); err == nil {
+			c.clock = t * 10
+		}
+	case "otim":
+	case "undo":
+		c.cancelSearch()
+		c.takeBack(1)
+	case "remove":
+		c.cancelSearch()
+		c.takeBack(2)
+	case "result":
+		c.cancelSearch()
+		c.force = true
+	case "post":
+		c.post = true
+	case "nopost":
+		c.post = false
+	default:
+		fmt.Println("Error (unknown command):", cmd)
+	}
+
+	return ""
+}
+
+func (c *CECPinterface) mode() string {
+	return "xboard"
+}
+
+//commands that change the game stop the search and throw its move away, so they can run straight away.
+func (c *CECPinterface) duringSearch(cmd string) bool {
+	switch cmd {
+	case "?", "ping", "quit", "force", "new", "setboard", "result", "undo", "remove":
+		return true
+	}
+	return false
+}
+
+//plays the move the search found
+func (c *CECPinterface) searchFinished(bestVariation board.MoveList) {
+	if len(bestVariation) == 0 {
+		return
+	}
+	fmt.Println("move", bestVariation[0].UCIstring())
+	c.play(bestVariation[0])
+	c.gameOver()
+}
+
+func (c *CECPinterface) cancelSearch() {
+	if c.searching() {
+		c.stopSearch()
+	}
+}
+
+func (c *CECPinterface) setBoard(fen string) error {
+	if err := c.setPosition(fen); err != nil {
+		return err
+	}
+	c.startFEN = fen
+	c.moves = nil
+	c.seen = map[uint64]int{c.game.Hash: 1}
+	return nil
+}
+
+func (c *CECPinterface) play(m board.Move) {
+	c.game.DoMove(m)
+	c.moves = append(c.moves, m)
+	c.seen[c.game.Hash]++
+}
+
+//there's no unmake, so take backs replay the game from the start
+func (c *CECPinterface) takeBack(n int) {
+	if n > len(c.moves) {
+		fmt.Println("Error (no moves to undo):", n)
+		return
+	}
+	moves := c.moves[:len(c.moves)-n]
+	c.setBoard(c.startFEN)
+	for _, m := range moves {
+		c.play(m)
+	}
+}
+
+//reports the result if the game has ended
+func (c *CECPinterface) gameOver() bool {
+	if moves, _ := board.Movegen(&c.game); len(moves) == 0 {
+		outcome := c.game.Outcome()
+		if outcome == board.Drawn {
+			fmt.Println("1/2-1/2 {Stalemate}")
+			return true
+		}
+		winner := c.game.ToMove
+		if outcome == board.Lost {
+			winner = board.Opponent(winner)
+		}
+		result, reason := "1-0", "White"
+		if winner == board.BLACK {
+			result, reason = "0-1", "Black"
+		}
+		if outcome == board.Lost && c.game.InCheck() {
+			reason += " mates"
+		} else {
+			reason += " wins by the " + cecpVariantNames[c.game.Variant] + " rules"
+		}
+		fmt.Printf("%s {%s}\n", result, reason)
+		return true
+	}
+	if c.game.FiftyMoveCounter >= 100 {
+		fmt.Println("1/2-1/2 {Fifty move rule}")
+		return true
+	}
+	if c.seen[c.game.Hash] >= 3 {
+		fmt.Println("1/2-1/2 {Threefold repetition}")
+		return true
+	}
+	return false
+}
+
+func (c *CECPinterface) think() {
+	limits := search.SearchLimits{Depth: c.maxDepth, MultiPV: 1}
+	if c.moveTime > 0 {
+		limits.MoveTime = c.moveTime
+	} else if c.clock > 0 {
+		limits.Time, limits.Increment = c.clock, c.increment
+		if c.movesPerSession > 0 {
+			limits.MovesToGo = c.movesPerSession - (c.game.FullMoveCounter-1)%c.movesPerSession
+		}
+	} else if c.maxDepth == 0 {
+		limits.Infinite = true //no limits at all, so we think until we're told to move with ?
+	}
+	c.startSearch(limits)
+}
+
+//prints a line of thinking output: ply, score (centipawns, from the engine's point of view), time
+//(centiseconds), nodes and the PV. mate in n is scored 100000+n, the way xboard wants.
+func (c *CECPinterface) Iteration(p *board.Position, lines []search.Line, stats search.SearchStats) {
+	if c.post {
+		score := lines[0].Score * board.ScoreModifier[p.ToMove]
+		if n, ok := search.MateIn(score); ok && n >= 0 {
+			score = 100000 + n
+		} else if ok {
+			score = -100000 + n
+		}
+		fmt.Printf("%d %d %d %d %s\n", stats.Depth, score, stats.Time.Milliseconds()/10, stats.Nodes, lines[0].Variation.UCIvariation())
+	}
+}
+
+func (c *CECPinterface) CurrentMove(depth int, m board.Move, number int, elapsed time.Duration) {
+}
+
+func (c *CECPinterface) Hashfull(permille int) {
+}
+
+func (c *CECPinterface) Message(s string) {
+	if c.post {
+		fmt.Println("#", s)
+	}
+}
+
+//the move gets played by searchFinished, once we're back in the command loop
+func (c *CECPinterface) Finished(p *board.Position, lines []search.Line, stats search.SearchStats) {
+}
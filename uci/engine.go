@@ -0,0 +1,214 @@
+//Package uci runs an engine over the text protocols: UCI, XBoard (CECP) and a command line for humans.
+package uci
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+	"github.com/BenNicholls/Aristocrat2/search"
+)
+
+//Engine plays chess over one of the text interfaces: UCI, XBoard or the command line. it keeps the
+//game being played and searches it with its own search engine.
+type Engine struct {
+	search *search.Engine
+	mode   chessInterface
+	game   board.Position
+
+	chess960 bool          //UCI_Chess960: castling is sent and received as the king taking its rook
+	variant  board.Variant //UCI_Variant: the rules new positions are set up with
+
+	searchDone <-chan []search.Line //receives the lines found when the running search ends. nil if there isn't one
+	endless    bool                 //the running search only ends when it's stopped: go infinite, or pondering
+}
+
+//makes an engine that starts in the given mode, "uci" or "cli". it switches to XBoard by itself if
+//the first command it gets is "xboard".
+func NewEngine(mode string) (*Engine, error) {
+	e := &Engine{search: search.NewEngine(), game: board.NewPosition("")}
+	switch mode {
+	case "uci":
+		e.mode = &UCIinterface{e}
+	case "cli":
+		e.mode = &CLIinterface{e}
+	default:
+		return nil, errors.New("Unknown mode: " + mode)
+	}
+	return e, nil
+}
+
+//sets up the game from a FEN (or startpos) with the rules picked by the options. a bad FEN leaves the
+//game as it was.
+func (e *Engine) setPosition(fen string) error {
+	game, err := board.ParseVariantFEN(fen, e.variant)
+	if err != nil {
+		return err
+	}
+	e.game = game
+	e.game.Chess960 = e.game.Chess960 || e.chess960
+	return nil
+}
+
+//the search engine, for setting options before running
+func (e *Engine) Search() *search.Engine {
+	return e.search
+}
+
+//Run reads commands from input and runs them until input runs out or we're told to quit. either way the
+//commands already read still get run in order, and it returns once the last search has finished.
+//searches that would only end when stopped (go infinite, go ponder) are stopped, and report their
+//moves as usual.
+func (e *Engine) Run(input io.Reader) {
+	if e.mode.mode() == "cli" {
+		e.mode.processCommand("new", "")
+	}
+
+	lines := make(chan string)
+	quit := make(chan struct{}) //closed when we stop reading, so the reader doesn't wait on us forever
+	defer close(quit)
+	go func(lines chan<- string) {
+		defer close(lines)
+		commandReader := bufio.NewScanner(input)
+		for commandReader.Scan() {
+			select {
+			case lines <- commandReader.Text():
+			case <-quit:
+				return
+			}
+		}
+	}(lines)
+
+	//commands that can't run during a search are queued until it's done. once input is closed (piped
+	//commands, probably) or we've been told to quit, we stop reading and keep going until the queue is
+	//empty and the last search has finished.
+	var queue []string
+	firstLine := true
+	for lines != nil || e.searching() || len(queue) > 0 {
+		for !e.searching() && len(queue) > 0 {
+			line := queue[0]
+			queue = queue[1:]
+			if e.runCommand(line) {
+				lines = nil
+			}
+		}
+		if lines == nil {
+			if !e.searching() {
+				break
+			}
+			//nothing is left to stop it
+			if e.endless {
+				e.search.Stop()
+			}
+		}
+
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				lines = nil
+				break
+			}
+			logInput(line)
+			if firstLine && commandName(line) == "xboard" {
+				cecp := &CECPinterface{Engine: e}
+				cecp.setBoard("")
+				e.mode = cecp
+			}
+			firstLine = false
+			if e.searching() && !e.mode.duringSearch(commandName(line)) {
+				queue = append(queue, line)
+			} else if e.runCommand(line) {
+				lines = nil
+			}
+		case found := <-e.searchDone:
+			e.searchDone = nil
+			if handler, ok := e.mode.(resultHandler); ok {
+				handler.searchFinished(found[0].Variation)
+			}
+		}
+	}
+}
+
+func commandName(line string) string {
+	return strings.ToLower(strings.SplitN(strings.TrimSpace(line), " ", 2)[0])
+}
+
+//runs a line of input through the current interface. returns true if it's time to quit.
+func (e *Engine) runCommand(line string) (quit bool) {
+	cmd := strings.SplitN(strings.TrimSpace(line), " ", 2)
+	params := ""
+	if len(cmd) == 2 {
+		params = cmd[1]
+	}
+	engineResult := e.mode.processCommand(strings.ToLower(cmd[0]), params)
+	switch engineResult {
+	case "quit":
+		return true
+	case "uci":
+		e.mode = &UCIinterface{e}
+		e.mode.processCommand("uci", "")
+	case "cli":
+		e.mode = &CLIinterface{e}
+		e.mode.processCommand("new", "")
+	}
+	return false
+}
+
+//reports whether a search launched by the interfaces is running. only for use by the command loop.
+func (e *Engine) searching() bool {
+	return e.searchDone != nil
+}
+
+//launches a search of the game in the background. only one search runs at a time.
+func (e *Engine) startSearch(limits search.SearchLimits) {
+	e.searchDone = e.search.Start(&e.game, limits, e.mode)
+	e.endless = limits.Infinite || limits.Ponder
+}
+
+//stops the running search and waits for it to finish. the result goes nowhere, but the interface will
+//still have reported it.
+func (e *Engine) stopSearch() {
+	e.search.Stop()
+	<-e.searchDone
+	e.searchDone = nil
+}
+
+//prints the UCI bestmove for the variation, along with the move we expect in reply to ponder on.
+func printBestMove(variation board.MoveList) {
+	if len(variation) == 0 { //no legal moves
+		fmt.Println("bestmove 0000")
+	} else if len(variation) > 1 {
+		fmt.Println("bestmove", variation[0].UCIstring(), "ponder", variation[1].UCIstring())
+	} else {
+		fmt.Println("bestmove", variation[0].UCIstring())
+	}
+}
+
+//describes the score for display, taking the search result into account. score is from white's point
+//of view.
+func evalString(score int, r search.Result) string {
+	switch r {
+	case search.Checkmate:
+		if n, ok := search.MateIn(score); ok && n > 0 {
+			return fmt.Sprintf("White mates in %d", n)
+		} else if ok && n < 0 {
+			return fmt.Sprintf("Black mates in %d", -n)
+		} else if score > 0 {
+			return "White is mating"
+		}
+		return "Black is mating"
+	case search.Stalemate:
+		return "Stalemate"
+	case search.Tablebase:
+		if score > 0 {
+			return "Tablebase win for White"
+		} else if score < 0 {
+			return "Tablebase win for Black"
+		}
+		return "Tablebase draw"
+	}
+	return fmt.Sprintf("Eval: %.2f", float64(score)/100)
+}
@@ -0,0 +1,174 @@
+package uci
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+)
+
+//runs the engine on the input and returns the lines it prints. the interfaces print straight to
+//stdout, so it's swapped for a pipe while the engine runs.
+func run(t *testing.T, mode, input string) []string {
+	t.Helper()
+	return runFrom(t, mode, strings.NewReader(input))
+}
+
+func runFrom(t *testing.T, mode string, input io.Reader) []string {
+	t.Helper()
+	e, err := NewEngine(mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	output := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		output <- string(b)
+	}()
+
+	done := make(chan bool)
+	go func() {
+		e.Run(input)
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		e.search.Stop()
+		<-done
+		t.Error("Run didn't return")
+	}
+	os.Stdout = stdout
+	w.Close()
+	return strings.Split(strings.TrimSpace(<-output), "\n")
+}
+
+//the lines starting with prefix
+func linesWith(lines []string, prefix string) (found []string) {
+	for _, line := range lines {
+		if strings.HasPrefix(line, prefix) {
+			found = append(found, line)
+		}
+	}
+	return
+}
+
+//commands sent during a search wait for it, quit included, so every go gets its bestmove
+func TestCommandQueue(t *testing.T) {
+	out := run(t, "uci", "uci\ngo depth 4\nposition startpos moves e2e4\ngo depth 2\nquit\nisready\n")
+	moves := linesWith(out, "bestmove")
+	if len(moves) != 2 {
+		t.Fatalf("Expected 2 bestmoves, got %q", moves)
+	}
+	for i, fen := range []string{"startpos", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"} {
+		pos := board.NewPosition(fen)
+		if _, ok := board.ParseMove(&pos, strings.Fields(moves[i])[1]); !ok {
+			t.Errorf("%s isn't a move in %s", moves[i], fen)
+		}
+	}
+	if len(linesWith(out, "readyok")) != 0 {
+		t.Error("Command after quit was run")
+	}
+
+	//a search nothing can stop once input runs out is stopped, and still reports its move
+	for _, input := range []string{"position startpos\ngo infinite\n", "position startpos\ngo infinite\nquit\n"} {
+		if moves := linesWith(run(t, "uci", input), "bestmove"); len(moves) != 1 {
+			t.Errorf("Expected one bestmove for %q, got %q", input, moves)
+		}
+	}
+
+	//quit lets the search finish
+	if depths := linesWith(run(t, "cli", "search 4\nquit\n"), "4 |"); len(depths) != 1 {
+		t.Error("Search stopped before depth 4 on quit")
+	}
+}
+
+//a ponder search doesn't report its move until ponderhit or stop, even if it's finished
+func TestPonder(t *testing.T) {
+	for _, end := range []string{"ponderhit", "stop"} {
+		r, w := io.Pipe()
+		go func() {
+			io.WriteString(w, "position startpos\ngo ponder depth 1\n")
+			time.Sleep(100 * time.Millisecond)
+			io.WriteString(w, "isready\n"+end+"\n")
+			w.Close()
+		}()
+		out := runFrom(t, "uci", r)
+		last := out[len(out)-1]
+		if !strings.HasPrefix(last, "bestmove") || len(linesWith(out, "bestmove")) != 1 || len(linesWith(out, "readyok")) != 1 {
+			t.Errorf("One bestmove should come last, after %s: %q", end, out)
+		}
+	}
+	//after ponderhit it's a normal timed search
+	out := run(t, "uci", "position startpos\ngo ponder wtime 500 btime 500\nponderhit\n")
+	if moves := linesWith(out, "bestmove"); len(moves) != 1 {
+		t.Errorf("Expected one bestmove after ponderhit, got %q", moves)
+	}
+}
+
+//searchmoves takes moves until something that isn't one, so other limits can follow it
+func TestSearchMoves(t *testing.T) {
+	out := run(t, "uci", "position startpos\ngo searchmoves h2h3 a2a3 depth 3\n")
+	moves := linesWith(out, "bestmove")
+	if len(moves) != 1 || (!strings.HasPrefix(moves[0], "bestmove h2h3") && !strings.HasPrefix(moves[0], "bestmove a2a3")) {
+		t.Errorf("Best move should be h2h3 or a2a3: %q", moves)
+	}
+	if len(linesWith(out, "info depth 3")) == 0 || len(linesWith(out, "info depth 4")) != 0 {
+		t.Errorf("Depth after searchmoves wasn't read: %q", out)
+	}
+}
+
+//writes the parts of the input half a second apart, so there's time to think in between
+func staged(parts ...string) io.Reader {
+	r, w := io.Pipe()
+	go func() {
+		for i, part := range parts {
+			if i > 0 {
+				time.Sleep(500 * time.Millisecond)
+			}
+			io.WriteString(w, part)
+		}
+		w.Close()
+	}()
+	return r
+}
+
+func TestXBoard(t *testing.T) {
+	out := run(t, "uci", "xboard\nprotover 2\nping 7\nnew\nsd 2\nusermove e2e4\n")
+	if len(linesWith(out, "feature ")) != 1 || len(linesWith(out, "pong 7")) != 1 {
+		t.Errorf("Bad handshake: %q", out)
+	}
+	reply := linesWith(out, "move ")
+	pos := board.NewPosition("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1")
+	if len(reply) != 1 {
+		t.Fatalf("Expected one move in reply to e4, got %q", reply)
+	} else if _, ok := board.ParseMove(&pos, strings.TrimPrefix(reply[0], "move ")); !ok {
+		t.Errorf("%s isn't a reply to e4", reply[0])
+	}
+
+	//undo takes back one move and remove two. e2e4 is only legal again if they did
+	out = run(t, "uci", "xboard\nnew\nforce\nusermove e2e4\nusermove e7e5\nremove\nusermove e2e4\nundo\nusermove e2e4\nundo\nundo\n")
+	if errors := linesWith(out, "Error"); len(linesWith(out, "Illegal move")) != 0 || len(errors) != 1 || errors[0] != "Error (no moves to undo): 1" {
+		t.Errorf("Take backs went wrong: %q", out)
+	}
+
+	//st, sd and level each limit the search, so the move comes before the ping sent after it
+	for _, limit := range []string{"st 0.1", "sd 3\npost", "level 0 0:01 0\ntime 20"} {
+		out = runFrom(t, "uci", staged("xboard\nnew\n"+limit+"\ngo\n", "ping 1\n"))
+		if len(out) < 2 || !strings.HasPrefix(out[len(out)-2], "move ") || out[len(out)-1] != "pong 1" {
+			t.Errorf("%q didn't limit the search: %q", limit, out)
+		}
+		if limit == "sd 3\npost" && !strings.HasPrefix(out[len(out)-3], "3 ") {
+			t.Errorf("Last thinking output should be depth 3: %q", out)
+		}
+	}
+}
@@ -0,0 +1,445 @@
+package uci
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BenNicholls/Aristocrat2/board"
+	"github.com/BenNicholls/Aristocrat2/search"
+)
+
+type chessInterface interface {
+	processCommand(cmd, params string) string //channel to let us know when program can end
+	mode() string
+	duringSearch(cmd string) bool //whether cmd can be run while searching. others wait for the search to end
+	search.SearchReporter
+}
+
+//interfaces that need the result of their searches (to play the move, say) implement this. it's
+//called from the command loop once the search is done.
+type resultHandler interface {
+	searchFinished(bestVariation board.MoveList)
+}
+
+type CLIinterface struct {
+	*Engine
+}
+
+func (cli *CLIinterface) processCommand(cmd, params string) string {
+	switch cmd {
+	case "quit":
+		return "quit"
+	case "new":
+		cli.setPosition("")
+		cli.game.Output()
+	case "setboard":
+		if err := cli.setPosition(params); err != nil {
+			fmt.Println("Bad FEN:", err)
+			break
+		}
+		cli.game.Output()
+	case "variant": //variant <name>, then a new game
+		v, ok := board.ParseVariant(params)
+		if !ok {
+			fmt.Println("Unknown variant. Variants are:", strings.Join(board.VariantNames[:], ", "))
+			break
+		}
+		cli.variant = v
+		cli.setPosition("")
+		cli.game.Output()
+	case "display":
+		cli.game.Output()
+	case "eval":
+		fmt.Print(cli.search.Evaluator().TraceEval(&cli.game))
+	case "flip":
+		cli.game = cli.game.Mirror()
+		cli.game.Output()
+	case "divide":
+		if params != "" {
+			plys, err := strconv.Atoi(params)
+			if err == nil {
+				cli.game.Divide(plys, cli.search.PerftCache())
+			} else {
+				fmt.Println("Divide command argument must be integer")
+			}
+		} else {
+			fmt.Println("Divide command must have single argument (# of plys)")
+		}
+	case "perft":
+		if params != "" {
+			plys, err := strconv.Atoi(params)
+			if err == nil {
+				startTime := time.Now()
+				nodes := board.MultiThreadedPerft(&cli.game, plys, cli.search.PerftCache())
+				fmt.Printf("Perft %d: %d. (%s)\n", plys, nodes, board.NPS(nodes, time.Since(startTime).Seconds()))
+			} else {
+				fmt.Println("Perft command argument must be integer")
+			}
+		} else {
+			fmt.Println("Perft command must have single argument (# of plys)")
+		}
+	case "move":
+		if params != "" {
+			if m, ok := board.ParseMove(&cli.game, params); ok {
+				cli.game.DoMove(m)
+				cli.game.Output()
+			} else {
+				fmt.Println("Illegal move:", params)
+			}
+		}
+	case "search": //search <depth> [multipv <n>] [moves...]
+		args := strings.Fields(params)
+		if len(args) == 0 {
+			fmt.Println("search command must have a depth")
+			break
+		}
+		depth, err := strconv.Atoi(args[0])
+		if err != nil {
+			fmt.Println("search command argument must be integer")
+			break
+		}
+		lines := 1
+		args = args[1:]
+		if len(args) >= 2 && args[0] == "multipv" {
+			if lines, err = strconv.Atoi(args[1]); err != nil || lines < 1 {
+				fmt.Println("multipv must be a positive integer")
+				break
+			}
+			args = args[2:]
+		}
+		restrictTo := make(board.MoveList, 0, len(args))
+		for _, ms := range args {
+			if m, ok := board.ParseMove(&cli.game, ms); ok {
+				restrictTo = append(restrictTo, m)
+			} else {
+				fmt.Println("Illegal move:", ms)
+			}
+		}
+		if len(args) > 0 && len(restrictTo) == 0 {
+			break
+		}
+		cli.startSearch(search.SearchLimits{Depth: depth, MultiPV: lines, Moves: restrictTo})
+	case "book":
+		if params != "" {
+			if err := cli.search.LoadBook(params); err != nil {
+				fmt.Println("Could not open book:", err)
+			} else {
+				cli.search.OwnBook = true
+			}
+		}
+		if !cli.search.OutputBook(&cli.game) {
+			fmt.Println("No book loaded. Use \"book <file>\" to load one.")
+		}
+	case "syzygy":
+		fmt.Println(cli.search.LoadTablebases(params))
+	case "tbprobe":
+		if wdl, ok := cli.search.ProbeWDL(&cli.game); !ok {
+			fmt.Println("Position not in tablebases.")
+		} else {
+			fmt.Println("WDL:", wdl)
+			if dtz, ok := cli.search.ProbeDTZ(&cli.game); ok {
+				fmt.Println("DTZ:", dtz)
+			}
+		}
+	case "uci":
+		return "uci"
+	case "stop":
+		if cli.searching() {
+			cli.search.Stop()
+		} else {
+			fmt.Println("Engine not calculating")
+		}
+	}
+
+	fmt.Print(">>> ")
+	return ""
+}
+
+func (cli *CLIinterface) mode() string {
+	return "cli"
+}
+
+func (cli *CLIinterface) duringSearch(cmd string) bool {
+	return cmd == "stop" || cmd == "quit"
+}
+
+func (cli *CLIinterface) Iteration(p *board.Position, lines []search.Line, stats search.SearchStats) {
+	if len(lines) == 1 {
+		fmt.Printf("%d | %s | Variation: %s\n", stats.Depth, evalString(lines[0].Score, lines[0].Result), lines[0].Variation.Variation())
+	} else {
+		fmt.Printf("Depth %d\n", stats.Depth)
+		for i, line := range lines {
+			fmt.Printf("%3d | %-24s | %s\n", i+1, evalString(line.Score, line.Result), line.Variation.Variation())
+		}
+	}
+	fmt.Printf("searched %d nodes in %.3fs (%s)\n", stats.Nodes, stats.Time.Seconds(), board.NPS(stats.Nodes, stats.Time.Seconds()))
+}
+
+func (cli *CLIinterface) CurrentMove(depth int, m board.Move, number int, elapsed time.Duration) {
+}
+
+func (cli *CLIinterface) Hashfull(permille int) {
+}
+
+func (cli *CLIinterface) Message(s string) {
+	fmt.Println(s)
+}
+
+func (cli *CLIinterface) Finished(p *board.Position, lines []search.Line, stats search.SearchStats) {
+}
+
+type UCIinterface struct {
+	*Engine
+}
+
+func (uci *UCIinterface) processCommand(cmd, params string) string {
+	switch cmd {
+	case "uci":
+		fmt.Println("id name Aristocrat")
+		fmt.Println("id author Benjamin Nicholls")
+		fmt.Println("option name Hash type spin default 2 min 0 max 512 ")
+		fmt.Println("option name Threads type spin default 1 min 1 max 64")
+		fmt.Println("option name OwnBook type check default false")
+		fmt.Println("option name BookFile type string default <empty>")
+		fmt.Println("option name BookDepth type spin default 20 min 1 max 200")
+		fmt.Println("option name SyzygyPath type string default <empty>")
+		fmt.Println("option name MultiPV type spin default 1 min 1 max 64")
+		fmt.Println("option name WeightsFile type string default <empty>")
+		fmt.Println("option name EvalFile type string default <empty>")
+		fmt.Println("option name UCI_Chess960 type check default false")
+		fmt.Println("option name UCI_Variant type combo default chess var " + strings.Join(board.VariantNames[:], " var "))
+		fmt.Println("option name Ponder type check default false")
+		fmt.Println("uciok")
+	case "debug":
+	case "isready":
+		fmt.Println("readyok")
+	case "setoption":
+		//format is: name <id> [value <x>]. names and values can both contain spaces
+		if !strings.HasPrefix(params, "name ") {
+			return ""
+		}
+		optionName, value, _ := strings.Cut(strings.TrimPrefix(params, "name "), " value ")
+		optionName, value = strings.TrimSpace(optionName), strings.TrimSpace(value)
+
+		switch strings.ToLower(optionName) {
+		case "hash":
+			size, err := strconv.Atoi(value)
+			if err != nil {
+				return ""
+			}
+			uci.search.SetHashSize(size)
+			fmt.Println("info string Hashtable set to ", size, "MB")
+		case "threads":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return ""
+			}
+			uci.search.Threads = n
+		case "ownbook":
+			uci.search.OwnBook = value == "true"
+		case "bookfile":
+			if value == "<empty>" {
+				value = ""
+			}
+			if err := uci.search.LoadBook(value); err != nil {
+				fmt.Println("info string ERROR: could not open book:", err)
+			}
+		case "bookdepth":
+			if d, err := strconv.Atoi(value); err == nil && d > 0 {
+				uci.search.BookDepth = d
+			}
+		case "syzygypath":
+			if value == "<empty>" {
+				value = ""
+			}
+			fmt.Println("info string", uci.search.LoadTablebases(value))
+		case "multipv":
+			if n, err := strconv.Atoi(value); err == nil && n > 0 {
+				uci.search.MultiPV = n
+			}
+		case "weightsfile":
+			if value == "<empty>" {
+				value = ""
+			}
+			if err := uci.search.LoadWeights(value); err != nil {
+				fmt.Println("info string ERROR: could not load weights:", err)
+			}
+		case "evalfile":
+			if value == "<empty>" {
+				value = ""
+			}
+			if err := uci.search.LoadNetwork(value); err != nil {
+				fmt.Println("info string ERROR: could not load network, using the classical eval:", err)
+				uci.search.LoadNetwork("")
+			}
+		case "uci_chess960":
+			uci.chess960 = value == "true"
+		case "uci_variant":
+			if v, ok := board.ParseVariant(value); ok {
+				uci.variant = v
+			} else {
+				fmt.Println("info string ERROR: unknown variant", value)
+			}
+		case "ponder":
+			//nothing to do, the gui decides when to send go ponder
+		}
+	case "ucinewgame":
+	case "position":
+		s := strings.Split(strings.TrimPrefix(params, "fen "), " moves ")
+		if err := uci.setPosition(s[0]); err != nil {
+			fmt.Println("info string ERROR: bad FEN:", err)
+			break
+		}
+		if len(s) == 2 {
+			for _, ms := range strings.Fields(s[1]) {
+				m, ok := board.ParseMove(&uci.game, ms)
+				if !ok {
+					fmt.Println("info string ERROR: illegal move", ms)
+					break
+				}
+				uci.game.DoMove(m)
+			}
+		}
+	case "go":
+		words := strings.Fields(params)
+		var limits search.SearchLimits
+		var clock, increment [2]int
+		//reads the int value following words[i]
+		intValue := func(i int, name string) (int, bool) {
+			if i+1 >= len(words) {
+				fmt.Printf("info string ERROR: could not parse %s. (no value?)\n", name)
+				return 0, false
+			}
+			v, err := strconv.Atoi(words[i+1])
+			if err != nil {
+				fmt.Printf("info string ERROR: could not parse %s value. (not an int?)\n", name)
+				return 0, false
+			}
+			return v, true
+		}
+		for i := 0; i < len(words); i++ {
+			switch words[i] {
+			case "infinite":
+				limits.Infinite = true
+			case "depth":
+				if d, ok := intValue(i, "depth"); ok {
+					limits.Depth = d
+					i++
+				}
+			case "movetime":
+				if t, ok := intValue(i, "time"); ok {
+					limits.MoveTime = t
+					i++
+				}
+			case "wtime", "btime", "winc", "binc":
+				if t, ok := intValue(i, words[i]); ok {
+					colour := board.WHITE
+					if words[i][0] == 'b' {
+						colour = board.BLACK
+					}
+					if strings.HasSuffix(words[i], "time") {
+						clock[colour] = t
+					} else {
+						increment[colour] = t
+					}
+					i++
+				}
+			case "nodes":
+				if n, ok := intValue(i, "nodes"); ok {
+					limits.Nodes = n
+					i++
+				}
+			case "mate":
+				if n, ok := intValue(i, "mate"); ok {
+					limits.MateIn = n
+					i++
+				}
+			case "movestogo":
+				if n, ok := intValue(i, "movestogo"); ok {
+					limits.MovesToGo = n
+					i++
+				}
+			case "ponder":
+				limits.Ponder = true
+			case "searchmoves":
+				//moves continue until we hit something that isn't one
+				for i+1 < len(words) {
+					m, ok := board.ParseMove(&uci.game, words[i+1])
+					if !ok {
+						break
+					}
+					limits.Moves = append(limits.Moves, m)
+					i++
+				}
+			}
+		}
+		limits.Time, limits.Increment = clock[uci.game.ToMove], increment[uci.game.ToMove]
+		if limits.Depth == 0 && limits.MoveTime == 0 && limits.Nodes == 0 && limits.MateIn == 0 && limits.Time == 0 && !limits.Infinite && !limits.Ponder {
+			fmt.Println("info string Not sure what to do here. Search for 8 plys I guess?")
+		}
+		uci.startSearch(limits)
+	case "stop":
+		uci.search.Stop()
+	case "ponderhit":
+		uci.search.PonderHit()
+		uci.endless = false
+	case "quit":
+		return "quit"
+	case "eval": //not part of uci, but handy for seeing what the eval thinks
+		fmt.Print(uci.search.Evaluator().TraceEval(&uci.game))
+	case "cli": //return to command line mode
+		return "cli"
+	}
+
+	return ""
+}
+
+func (uci UCIinterface) mode() string {
+	return "uci"
+}
+
+//scores are from the engine's point of view, as UCI wants
+func (uci *UCIinterface) Iteration(p *board.Position, lines []search.Line, stats search.SearchStats) {
+	for i, line := range lines {
+		multiPVString := ""
+		if uci.search.MultiPV > 1 {
+			multiPVString = fmt.Sprintf(" multipv %d", i+1)
+		}
+		score := fmt.Sprintf("cp %d", line.Score*board.ScoreModifier[p.ToMove])
+		if n, ok := search.MateIn(line.Score * board.ScoreModifier[p.ToMove]); ok {
+			score = fmt.Sprintf("mate %d", n)
+		}
+		fmt.Printf("info depth %d%s score %s nodes %d nps %.0f tbhits %d time %d pv %s\n", stats.Depth, multiPVString, score, stats.Nodes, stats.NPS(), stats.TBHits, stats.Time.Milliseconds(), line.Variation.UCIvariation())
+	}
+}
+
+//only reported once the search has been going a while, otherwise it's just noise
+func (uci *UCIinterface) CurrentMove(depth int, m board.Move, number int, elapsed time.Duration) {
+	if elapsed > time.Second {
+		fmt.Printf("info depth %d currmove %s currmovenumber %d\n", depth, m.UCIstring(), number)
+	}
+}
+
+func (uci *UCIinterface) Hashfull(permille int) {
+	fmt.Println("info hashfull", permille)
+}
+
+func (uci *UCIinterface) Message(s string) {
+	fmt.Println("info string", s)
+}
+
+func (uci *UCIinterface) Finished(p *board.Position, lines []search.Line, stats search.SearchStats) {
+	printBestMove(lines[0].Variation)
+}
+
+//UCI says isready has to be answered straight away, even when searching. everything that changes the
+//position or options gets queued until the search is done.
+func (uci *UCIinterface) duringSearch(cmd string) bool {
+	switch cmd {
+	case "stop", "ponderhit", "isready", "quit":
+		return true
+	}
+	return false
+}
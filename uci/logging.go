@@ -0,0 +1,69 @@
+package uci
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+var logger struct {
+	sync.Mutex
+	file *os.File
+}
+
+//StartLogging starts logging everything the engine receives and prints to the given file. output is captured by
+//swapping stdout for a pipe, so all the fmt.Print calls everywhere keep working as normal. the
+//returned function restores stdout and flushes the log; it must be called before the program exits.
+func StartLogging(filename string) (stop func(), err error) {
+	logFile, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		logFile.Close()
+		return nil, err
+	}
+
+	logger.file = logFile
+	stdout := os.Stdout
+	os.Stdout = w
+
+	done := make(chan bool)
+	go func() {
+		io.Copy(io.MultiWriter(stdout, logWriter{}), r)
+		done <- true
+	}()
+
+	stop = func() {
+		os.Stdout = stdout
+		w.Close()
+		<-done
+		logger.Lock()
+		logger.file.Close()
+		logger.file = nil
+		logger.Unlock()
+	}
+	return
+}
+
+//records a line of input in the log, if we're logging.
+func logInput(line string) {
+	logger.Lock()
+	if logger.file != nil {
+		logger.file.WriteString(">> " + line + "\n")
+	}
+	logger.Unlock()
+}
+
+//logWriter writes engine output to the log file
+type logWriter struct{}
+
+func (logWriter) Write(b []byte) (int, error) {
+	logger.Lock()
+	defer logger.Unlock()
+	if logger.file == nil {
+		return len(b), nil
+	}
+	return logger.file.Write(b)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+//just enough of RFC 6455 to stream analysis: text messages both ways, ping/pong and close. no
+//extensions or subprotocols.
+
+const WEBSOCKETGUID string = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+//biggest message we'll accept from a client. all they send is small bits of JSON
+const WEBSOCKETMAXMESSAGE int = 1 << 16
+
+const (
+	WS_CONTINUATION byte = 0x0
+	WS_TEXT         byte = 0x1
+	WS_BINARY       byte = 0x2
+	WS_CLOSE        byte = 0x8
+	WS_PING         byte = 0x9
+	WS_PONG         byte = 0xA
+)
+
+type webSocket struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	writeLock sync.Mutex //search output and control frames can be written from different goroutines
+	closed    bool       //we've sent a close frame, so nothing else can be sent
+}
+
+//does the opening handshake and takes over the connection from the http server
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (ws *webSocket, err error) {
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "expected a websocket upgrade", http.StatusBadRequest)
+		return nil, errors.New("Not a websocket request")
+	}
+	if r.Header.Get("Sec-Websocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusBadRequest)
+		return nil, errors.New("Unsupported websocket version")
+	}
+	key := r.Header.Get("Sec-Websocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, errors.New("Missing websocket key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "can't upgrade this connection", http.StatusInternalServerError)
+		return nil, errors.New("Connection can't be hijacked")
+	}
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha1.Sum([]byte(key + WEBSOCKETGUID))
+	buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	buf.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(hash[:]) + "\r\n\r\n")
+	if err := buf.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &webSocket{conn: conn, reader: buf.Reader}, nil
+}
+
+func headerContains(h http.Header, name, value string) bool {
+	for _, v := range h.Values(name) {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//reads the next text or binary message, answering pings along the way. returns io.EOF once the client
+//has closed the connection.
+func (ws *webSocket) readMessage() (message []byte, err error) {
+	for {
+		fin, opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case WS_PING:
+			ws.writeFrame(WS_PONG, payload)
+			continue
+		case WS_PONG:
+			continue
+		case WS_CLOSE:
+			ws.writeFrame(WS_CLOSE, payload)
+			return nil, io.EOF
+		case WS_CONTINUATION:
+			if message == nil {
+				return nil, errors.New("Continuation frame without a message to continue")
+			}
+		case WS_TEXT, WS_BINARY:
+			if message != nil {
+				return nil, errors.New("New message before the last one was finished")
+			}
+			message = []byte{}
+		default:
+			return nil, errors.New("Unknown websocket opcode")
+		}
+
+		message = append(message, payload...)
+		if len(message) > WEBSOCKETMAXMESSAGE {
+			return nil, errors.New("Websocket message too big")
+		}
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+func (ws *webSocket) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(ws.reader, header[:]); err != nil {
+		return
+	}
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7F)
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(ws.reader, ext[:]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(ws.reader, ext[:]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > uint64(WEBSOCKETMAXMESSAGE) {
+		err = errors.New("Websocket frame too big")
+		return
+	}
+	if !masked { //clients always have to mask
+		err = errors.New("Unmasked frame from client")
+		return
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(ws.reader, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(ws.reader, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return
+}
+
+func (ws *webSocket) writeMessage(message []byte) error {
+	return ws.writeFrame(WS_TEXT, message)
+}
+
+//each write is sent as one text message
+func (ws *webSocket) Write(message []byte) (int, error) {
+	if err := ws.writeMessage(message); err != nil {
+		return 0, err
+	}
+	return len(message), nil
+}
+
+//servers never mask their frames, and we never fragment
+func (ws *webSocket) writeFrame(opcode byte, payload []byte) error {
+	frame := make([]byte, 0, len(payload)+10)
+	frame = append(frame, 0x80|opcode)
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		frame = append(frame, 126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(len(payload)))
+	default:
+		frame = append(frame, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(len(payload)))
+	}
+	frame = append(frame, payload...)
+
+	ws.writeLock.Lock()
+	defer ws.writeLock.Unlock()
+	if ws.closed {
+		return errors.New("Websocket is closed")
+	}
+	ws.closed = opcode == WS_CLOSE
+	_, err := ws.conn.Write(frame)
+	return err
+}
+
+func (ws *webSocket) close() error {
+	ws.writeFrame(WS_CLOSE, nil)
+	return ws.conn.Close()
+}